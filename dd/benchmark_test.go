@@ -0,0 +1,213 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package dd_example
+
+import (
+	"testing"
+	"time"
+
+	"github.com/51Degrees/device-detection-examples-go/v4/dd/internal/testutil"
+	"github.com/51Degrees/device-detection-examples-go/v4/onpremise/common"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+	"github.com/51Degrees/device-detection-go/v4/onpremise"
+)
+
+// Performance profiles the benchmarks are run with
+var benchmarkProfiles = []dd.PerformanceProfile{
+	dd.LowMemory,
+	dd.Balanced,
+	dd.BalancedTemp,
+	dd.HighPerformance,
+	dd.InMemory,
+}
+
+// runProfiles runs a benchmark with each of the performance profiles.
+func runProfiles(b *testing.B, run func(b *testing.B, p dd.PerformanceProfile)) {
+	for _, p := range benchmarkProfiles {
+		p := p
+		b.Run(ProfileName(p), func(b *testing.B) {
+			b.ReportAllocs()
+			run(b, p)
+		})
+	}
+}
+
+// benchmarkManager returns a resource manager initialised with the Lite data
+// file and the performance profile, which is freed when the benchmark ends.
+func benchmarkManager(b *testing.B, p dd.PerformanceProfile) *dd.ResourceManager {
+	filePath := testutil.DataFilePathOrSkip(b)
+	manager := dd.NewResourceManager()
+	config := dd.NewConfigHash(p)
+	if err := dd.InitManagerFromFile(manager, *config, "", filePath); err != nil {
+		b.Fatalf("Failed to initialize resource manager. %v", err)
+	}
+	b.Cleanup(manager.Free)
+	return manager
+}
+
+// benchmarkResults returns results for the manager which are freed when the
+// benchmark ends. The same results are used by each iteration so that only
+// the detection is measured.
+func benchmarkResults(b *testing.B, manager *dd.ResourceManager) *dd.ResultsHash {
+	results := dd.NewResultsHash(manager, uint32(len(common.ExampleEvidence1)), 0)
+	b.Cleanup(results.Free)
+	return results
+}
+
+// benchmarkUserAgents returns the User-Agents of the fixtures.
+func benchmarkUserAgents(b *testing.B) []string {
+	fixtures, err := Fixtures()
+	if err != nil {
+		b.Fatal(err)
+	}
+	uas := make([]string, 0, len(fixtures))
+	for _, f := range fixtures {
+		if ua := f.Record["header.user-agent"]; ua != "" {
+			uas = append(uas, ua)
+		}
+	}
+	return uas
+}
+
+// clientHintsEvidence returns the example evidence with User-Agent Client
+// Hints, which is freed when the benchmark ends.
+func clientHintsEvidence(b *testing.B) *dd.Evidence {
+	evidence := dd.NewEvidenceHash(uint32(len(common.ExampleEvidence1)))
+	for _, e := range common.ExampleEvidence1 {
+		if err := evidence.Add(e.Prefix, e.Key, e.Value); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.Cleanup(evidence.Free)
+	return evidence
+}
+
+// reportRate reports the number of operations per second since the start, as
+// the unit given.
+func reportRate(b *testing.B, start time.Time, operations int, unit string) {
+	if elapsed := time.Since(start).Seconds(); elapsed > 0 {
+		b.ReportMetric(float64(operations)/elapsed, unit)
+	}
+}
+
+func BenchmarkMatchUserAgent(b *testing.B) {
+	runProfiles(b, func(b *testing.B, p dd.PerformanceProfile) {
+		results := benchmarkResults(b, benchmarkManager(b, p))
+		uas := benchmarkUserAgents(b)
+		b.ResetTimer()
+		start := time.Now()
+		for i := 0; i < b.N; i++ {
+			if err := results.MatchUserAgent(uas[i%len(uas)]); err != nil {
+				b.Fatal(err)
+			}
+		}
+		reportRate(b, start, b.N, "detections/sec")
+	})
+}
+
+func BenchmarkMatchEvidenceClientHints(b *testing.B) {
+	runProfiles(b, func(b *testing.B, p dd.PerformanceProfile) {
+		results := benchmarkResults(b, benchmarkManager(b, p))
+		evidence := clientHintsEvidence(b)
+		b.ResetTimer()
+		start := time.Now()
+		for i := 0; i < b.N; i++ {
+			if err := results.MatchEvidence(evidence); err != nil {
+				b.Fatal(err)
+			}
+		}
+		reportRate(b, start, b.N, "detections/sec")
+	})
+}
+
+func BenchmarkMatchDeviceId(b *testing.B) {
+	runProfiles(b, func(b *testing.B, p dd.PerformanceProfile) {
+		results := benchmarkResults(b, benchmarkManager(b, p))
+		if err := results.MatchEvidence(clientHintsEvidence(b)); err != nil {
+			b.Fatal(err)
+		}
+		deviceId, err := results.DeviceId()
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		start := time.Now()
+		for i := 0; i < b.N; i++ {
+			if err := results.MatchDeviceId(deviceId); err != nil {
+				b.Fatal(err)
+			}
+		}
+		reportRate(b, start, b.N, "detections/sec")
+	})
+}
+
+// Each iteration gets the values of all the available properties.
+func BenchmarkValuesString(b *testing.B) {
+	runProfiles(b, func(b *testing.B, p dd.PerformanceProfile) {
+		results := benchmarkResults(b, benchmarkManager(b, p))
+		if err := results.MatchEvidence(clientHintsEvidence(b)); err != nil {
+			b.Fatal(err)
+		}
+		properties := results.AvailableProperties()
+		b.ResetTimer()
+		start := time.Now()
+		for i := 0; i < b.N; i++ {
+			for _, property := range properties {
+				if _, err := results.ValuesString(property, ","); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+		reportRate(b, start, b.N*len(properties), "values/sec")
+	})
+}
+
+// Each iteration creates and frees the evidence and results, as
+// Engine.Process does for each request.
+func BenchmarkEngineProcess(b *testing.B) {
+	runProfiles(b, func(b *testing.B, p dd.PerformanceProfile) {
+		filePath := testutil.DataFilePathOrSkip(b)
+		engine, err := onpremise.New(
+			onpremise.WithDataFile(filePath),
+			onpremise.WithConfigHash(dd.NewConfigHash(p)),
+			onpremise.WithAutoUpdate(false),
+			onpremise.WithFileWatch(false),
+			onpremise.WithTempDataCopy(false),
+			onpremise.WithLogging(false))
+		if err != nil {
+			b.Fatalf("Failed to create engine: %v", err)
+		}
+		b.Cleanup(engine.Stop)
+		b.ResetTimer()
+		start := time.Now()
+		for i := 0; i < b.N; i++ {
+			results, err := engine.Process(common.ExampleEvidence1)
+			if err != nil {
+				b.Fatal(err)
+			}
+			results.Free()
+		}
+		reportRate(b, start, b.N, "detections/sec")
+	})
+}
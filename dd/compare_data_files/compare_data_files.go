@@ -0,0 +1,353 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package main
+
+/*
+This example illustrates how to quantify the value of the Enterprise data file
+over the Lite data file for a corpus of Evidence Records, such as traffic
+captured by the web examples.
+
+Every record is processed with both data files and the following is reported:
+  - the properties which are only in one of the data files,
+  - for each property in either data file, the share of records it has values
+    for with each data file, and the share gained where only the Enterprise
+    data file has values, with a dash for a data file without the property,
+  - for each property, the share of records with values from both data files
+    where the values disagree, with examples of the disagreements.
+
+Records deduplicated by evidence_corpus with `-count` are weighted by the
+number of times they occurred, so shares are of traffic rather than of
+distinct records.
+
+To run this example, perform the following command:
+```
+go run compare_data_files.go -e "../20000 Evidence Records.yml"
+```
+*/
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	dd_example "github.com/51Degrees/device-detection-examples-go/v4/dd"
+	"gopkg.in/yaml.v3"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+type options struct {
+	LiteFilePath       string
+	EnterpriseFilePath string
+	EvidenceFilePath   string
+	Examples           int
+	showHelp           bool
+}
+
+func parseOptions() options {
+	o := options{}
+
+	flag.StringVar(&o.LiteFilePath, "lite", "../"+dd_example.LiteDataFile, "Path to the Lite data file")
+	flag.StringVar(&o.EnterpriseFilePath, "enterprise", "../"+dd_example.EnterpriseDataFile, "Path to the Enterprise data file")
+
+	flag.StringVar(&o.EvidenceFilePath, "evidence-file", "../"+dd_example.EvidenceFileYaml, "Path to a Evidence Records YAML file")
+	flag.StringVar(&o.EvidenceFilePath, "e", o.EvidenceFilePath, "Alias for -evidence-file")
+
+	flag.IntVar(&o.Examples, "examples", 10, "Number of disagreements to list")
+
+	flag.BoolVar(&o.showHelp, "help", false, "Print help")
+	flag.BoolVar(&o.showHelp, "h", o.showHelp, "Alias for -help")
+
+	flag.Parse()
+	return o
+}
+
+// coverage of a property by both data files.
+type coverage struct {
+	inLite       bool
+	inEnterprise bool
+	lite         uint64
+	enterprise   uint64
+	// Records only the Enterprise data file has values for
+	gained uint64
+	// Records both data files have values for, and those which disagree
+	compared  uint64
+	disagreed uint64
+}
+
+// disagreement is a record the data files detect different values for.
+type disagreement struct {
+	property   string
+	lite       string
+	enterprise string
+	userAgent  string
+}
+
+// report of the comparison of the data files.
+type report struct {
+	records        uint64
+	onlyLite       []string
+	onlyEnterprise []string
+	properties     map[string]*coverage
+	names          []string
+	examples       []disagreement
+	nExamples      int
+}
+
+// newReport returns a report for the properties of each data file.
+func newReport(lite, enterprise []string, nExamples int) *report {
+	r := &report{properties: make(map[string]*coverage), nExamples: nExamples}
+	get := func(p string) *coverage {
+		c, ok := r.properties[p]
+		if !ok {
+			c = &coverage{}
+			r.properties[p] = c
+			r.names = append(r.names, p)
+		}
+		return c
+	}
+	for _, p := range lite {
+		get(p).inLite = true
+	}
+	for _, p := range enterprise {
+		get(p).inEnterprise = true
+	}
+	sort.Strings(r.names)
+	for _, p := range r.names {
+		c := r.properties[p]
+		if !c.inEnterprise {
+			r.onlyLite = append(r.onlyLite, p)
+		} else if !c.inLite {
+			r.onlyEnterprise = append(r.onlyEnterprise, p)
+		}
+	}
+	return r
+}
+
+// add counts the values of the properties in both data files for a record.
+func (r *report) add(
+	lite, enterprise map[string]dd_example.PropertyValue,
+	userAgent string,
+	weight uint64) {
+	r.records += weight
+	// Properties in order so the examples are the same every time
+	for _, p := range r.names {
+		c := r.properties[p]
+		l, e := lite[p], enterprise[p]
+		// A data file has no values for a property it does not contain
+		hasLite := c.inLite && l.HasValue()
+		hasEnterprise := c.inEnterprise && e.HasValue()
+		if hasLite {
+			c.lite += weight
+		}
+		if hasEnterprise {
+			c.enterprise += weight
+			if !hasLite {
+				c.gained += weight
+			}
+		}
+		if !hasLite || !hasEnterprise {
+			continue
+		}
+		c.compared += weight
+		if l.Value() == e.Value() {
+			continue
+		}
+		c.disagreed += weight
+		if len(r.examples) < r.nExamples {
+			r.examples = append(r.examples, disagreement{
+				property:   p,
+				lite:       l.Value(),
+				enterprise: e.Value(),
+				userAgent:  userAgent,
+			})
+		}
+	}
+}
+
+// share returns n as a percentage of total.
+func share(n, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total) * 100
+}
+
+// percentage returns n as a percentage of total, or a dash if the property is
+// not in the data file.
+func percentage(in bool, n, total uint64) string {
+	if !in {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f%%", share(n, total))
+}
+
+// availableProperties returns the properties of a data file.
+func availableProperties(manager *dd.ResourceManager) []string {
+	results := dd_example.NewTrackedResults(manager, 0, 0)
+	defer results.Free()
+	return results.AvailableProperties()
+}
+
+// detect performs a detection on a record and returns the values of the
+// properties.
+func detect(
+	manager *dd.ResourceManager,
+	record map[string]string,
+	properties []string) map[string]dd_example.PropertyValue {
+	evidence := dd_example.ExtractEvidence(dd_example.ConvertEvidenceMap(record))
+	defer evidence.Free()
+	results := dd_example.NewTrackedResults(manager, uint32(evidence.Count()), 0)
+	defer results.Free()
+
+	if err := results.MatchEvidence(evidence.Evidence); err != nil {
+		dd_example.Fatalf("ERROR: Failed to perform detection. %v\n", err)
+	}
+	values := make(map[string]dd_example.PropertyValue, len(properties))
+	for _, p := range properties {
+		values[p] = dd_example.GetPropertyValue(results.ResultsHash, p)
+	}
+	return values
+}
+
+// compare processes every record of an Evidence Records file with both data
+// files.
+func compare(
+	lite, enterprise *dd.ResourceManager,
+	evidenceFilePath string,
+	nExamples int) *report {
+	file, err := os.Open(evidenceFilePath)
+	if err != nil {
+		dd_example.Fatalf("ERROR: Failed to open file \"%s\".\n", evidenceFilePath)
+	}
+	defer file.Close()
+
+	r := newReport(
+		availableProperties(lite),
+		availableProperties(enterprise),
+		nExamples)
+	dec := yaml.NewDecoder(file)
+	for {
+		// Decode Evidence file by line
+		var doc map[string]string
+		if err := dec.Decode(&doc); err == io.EOF {
+			break
+		} else if err != nil {
+			dd_example.Fatalf("ERROR: Failed during decoding file \"%s\". %v\n", evidenceFilePath, err)
+		}
+		r.add(
+			detect(lite, doc, r.names),
+			detect(enterprise, doc, r.names),
+			doc["header.user-agent"],
+			dd_example.RecordCount(doc))
+	}
+	return r
+}
+
+// print writes the report in human readable form.
+func (r *report) print(w io.Writer) {
+	fmt.Fprintf(w, "Records: %d\n", r.records)
+	fmt.Fprintf(w, "Properties in both data files: %d\n",
+		len(r.names)-len(r.onlyLite)-len(r.onlyEnterprise))
+	fmt.Fprintf(w, "Properties only in Lite: %d\n", len(r.onlyLite))
+	if len(r.onlyLite) > 0 {
+		fmt.Fprintf(w, "\t%s\n", strings.Join(r.onlyLite, ", "))
+	}
+	fmt.Fprintf(w, "Properties only in Enterprise: %d\n", len(r.onlyEnterprise))
+	if len(r.onlyEnterprise) > 0 {
+		fmt.Fprintf(w, "\t%s\n", strings.Join(r.onlyEnterprise, ", "))
+	}
+
+	// Properties gaining the most values first
+	properties := append([]string(nil), r.names...)
+	sort.Slice(properties, func(i, j int) bool {
+		a, b := r.properties[properties[i]], r.properties[properties[j]]
+		if a.gained != b.gained {
+			return a.gained > b.gained
+		}
+		return properties[i] < properties[j]
+	})
+
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Property\tLite\tEnterprise\tGained\tDisagree\t")
+	for _, p := range properties {
+		c := r.properties[p]
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t\n",
+			p,
+			percentage(c.inLite, c.lite, r.records),
+			percentage(c.inEnterprise, c.enterprise, r.records),
+			percentage(c.inEnterprise, c.gained, r.records),
+			percentage(c.inLite && c.inEnterprise, c.disagreed, c.compared))
+	}
+	tw.Flush()
+
+	if len(r.examples) > 0 {
+		fmt.Fprintf(w, "\nDisagreements:\n")
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "Property\tLite\tEnterprise\tUser-Agent\t")
+		for _, d := range r.examples {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n",
+				d.property, d.lite, d.enterprise, d.userAgent)
+		}
+		tw.Flush()
+	}
+}
+
+// initManager returns a resource manager for a data file with all of its
+// properties.
+func initManager(path string) *dd_example.TrackedManager {
+	manager := dd_example.NewTrackedManager()
+	config := dd.NewConfigHash(dd.Balanced)
+	err := dd.InitManagerFromFile(
+		manager.ResourceManager,
+		*config,
+		"",
+		dd_example.GetFilePathByPath(path))
+	if err != nil {
+		dd_example.Fatalln(err)
+	}
+	return manager
+}
+
+func main() {
+	defer dd_example.ReportLeaksAtExit()
+
+	o := parseOptions()
+	if o.showHelp {
+		flag.Usage()
+		return
+	}
+
+	lite := initManager(o.LiteFilePath)
+	defer lite.Free()
+	enterprise := initManager(o.EnterpriseFilePath)
+	defer enterprise.Free()
+
+	evidenceFilePath := dd_example.GetFilePathByPath(o.EvidenceFilePath)
+	compare(lite.ResourceManager, enterprise.ResourceManager, evidenceFilePath, o.Examples).print(os.Stdout)
+}
@@ -0,0 +1,99 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	dd_example "github.com/51Degrees/device-detection-examples-go/v4/dd"
+)
+
+// value returns a property value found, or with no value if empty.
+func value(v string) dd_example.PropertyValue {
+	if v == "" {
+		return dd_example.PropertyValue{Reason: dd_example.ValueNoValue}
+	}
+	return dd_example.PropertyValue{Values: []string{v}}
+}
+
+// Test that properties are split by the data files they are in, and that
+// coverage, gains and disagreements are counted with the weight of records,
+// including for properties in only one data file.
+func TestReport(t *testing.T) {
+	r := newReport(
+		[]string{"IsMobile", "BrowserName", "LiteOnly"},
+		[]string{"IsMobile", "BrowserName", "HardwareModel"},
+		1)
+	if !reflect.DeepEqual(r.onlyLite, []string{"LiteOnly"}) ||
+		!reflect.DeepEqual(r.onlyEnterprise, []string{"HardwareModel"}) ||
+		len(r.properties) != 4 {
+		t.Fatalf("Unexpected properties %v, %v and %v",
+			r.onlyLite, r.onlyEnterprise, r.properties)
+	}
+
+	for _, d := range []struct {
+		lite, enterprise string
+		weight           uint64
+	}{
+		{"Chrome", "Chrome", 5},
+		{"", "Chrome", 3},
+		{"Chrome", "Chrome Mobile", 2},
+	} {
+		r.add(
+			map[string]dd_example.PropertyValue{
+				"BrowserName": value(d.lite), "IsMobile": value("False"),
+				"LiteOnly": value(d.lite)},
+			map[string]dd_example.PropertyValue{
+				"BrowserName": value(d.enterprise), "IsMobile": value("False"),
+				"HardwareModel": value(d.enterprise)},
+			"ua",
+			d.weight)
+	}
+
+	for p, expected := range map[string]coverage{
+		"BrowserName": {inLite: true, inEnterprise: true,
+			lite: 7, enterprise: 10, gained: 3, compared: 7, disagreed: 2},
+		"LiteOnly":      {inLite: true, lite: 7},
+		"HardwareModel": {inEnterprise: true, enterprise: 10, gained: 10},
+	} {
+		if c := *r.properties[p]; c != expected {
+			t.Errorf("Expected %s coverage %+v, but got %+v", p, expected, c)
+		}
+	}
+	if c := *r.properties["IsMobile"]; c.disagreed != 0 || c.lite != 10 {
+		t.Errorf("Expected IsMobile to always agree, but got %+v", c)
+	}
+	if len(r.examples) != 1 || r.examples[0].enterprise != "Chrome Mobile" {
+		t.Errorf("Expected 1 disagreement example, but got %v", r.examples)
+	}
+
+	var b strings.Builder
+	r.print(&b)
+	if !strings.Contains(b.String(), "BrowserName    70.00%   100.00%     30.00%   28.57%") ||
+		!strings.Contains(b.String(), "HardwareModel  -        100.00%     100.00%  -") ||
+		!strings.Contains(b.String(), "LiteOnly       70.00%   -           -        -") {
+		t.Errorf("Unexpected report:\n%s", b.String())
+	}
+}
@@ -0,0 +1,232 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package dd_example
+
+/*
+This file contains functions to explain a device ID such as
+"12280-101474-102327-18092". A device ID is the ID of one profile per
+component of the data file, separated by '-', where 0 means no profile.
+
+The values of a single profile are found by matching a device ID which only
+contains that profile, e.g. "0-101474-0-0" for the platform. Properties of
+other components have no values for such an ID, so the component of a profile
+is named from the properties which do, rather than assuming the order of the
+components in the data file.
+*/
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+// Properties which only have values for the profiles of one component, and
+// the name of that component
+var componentProperties = []struct{ property, component string }{
+	{"HardwareVendor", "Hardware"},
+	{"DeviceType", "Hardware"},
+	{"IsMobile", "Hardware"},
+	{"PlatformName", "Platform"},
+	{"PlatformVendor", "Platform"},
+	{"BrowserName", "Browser"},
+	{"BrowserVendor", "Browser"},
+	{"IsCrawler", "Crawler"},
+	{"CrawlerName", "Crawler"},
+}
+
+// componentName returns the name of the component of a profile with the
+// property values given, or a name from its index in the device ID if none of
+// its properties identify the component, e.g. if the profile ID is 0.
+func componentName(index int, values map[string]string) string {
+	for _, cp := range componentProperties {
+		if _, ok := values[cp.property]; ok {
+			return cp.component
+		}
+	}
+	return fmt.Sprintf("Component %d", index)
+}
+
+// DeviceId is a device ID split into the profile ID of each component.
+type DeviceId struct {
+	ProfileIds []uint32
+}
+
+// ParseDeviceId splits a device ID into its profile IDs.
+func ParseDeviceId(s string) (DeviceId, error) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	ids := make([]uint32, len(parts))
+	for i, p := range parts {
+		id, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return DeviceId{}, fmt.Errorf("'%s' is not a valid device id", s)
+		}
+		ids[i] = uint32(id)
+	}
+	return DeviceId{ids}, nil
+}
+
+// Component returns a device ID with only the profile of the component at an
+// index of the device ID.
+func (d DeviceId) Component(index int) (DeviceId, error) {
+	if index < 0 || index >= len(d.ProfileIds) {
+		return DeviceId{}, fmt.Errorf(
+			"device id '%s' does not have a component at index %d", d, index)
+	}
+	ids := make([]uint32, len(d.ProfileIds))
+	ids[index] = d.ProfileIds[index]
+	return DeviceId{ids}, nil
+}
+
+// String returns the device ID in the form used by the engine.
+func (d DeviceId) String() string {
+	parts := make([]string, len(d.ProfileIds))
+	for i, id := range d.ProfileIds {
+		parts[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	return strings.Join(parts, "-")
+}
+
+// ComponentProfile is a profile of a device ID and its property values.
+type ComponentProfile struct {
+	Component string
+	ProfileId uint32
+	// Values of the properties of the component, separated by commas. Empty
+	// if the profile ID is 0.
+	Values map[string]string
+}
+
+// DecomposeDeviceId returns the profile of each component of a device ID with
+// the values of its properties.
+func DecomposeDeviceId(
+	manager *dd.ResourceManager,
+	id DeviceId) ([]ComponentProfile, error) {
+	results := NewTrackedResults(manager, 1, 0)
+	defer results.Free()
+
+	profiles := make([]ComponentProfile, len(id.ProfileIds))
+	for i, profileId := range id.ProfileIds {
+		profiles[i] = ComponentProfile{
+			ProfileId: profileId,
+			Values:    make(map[string]string),
+		}
+		if profileId != 0 {
+			if err := profileValues(results.ResultsHash, id, i, profiles[i].Values); err != nil {
+				return nil, err
+			}
+		}
+		profiles[i].Component = componentName(i, profiles[i].Values)
+	}
+	return profiles, nil
+}
+
+// profileValues adds the values of the properties of the profile at an index
+// of a device ID to values.
+func profileValues(
+	results *dd.ResultsHash,
+	id DeviceId,
+	index int,
+	values map[string]string) error {
+	component, err := id.Component(index)
+	if err != nil {
+		return err
+	}
+	if err := results.MatchDeviceId(component.String()); err != nil {
+		return err
+	}
+	for _, property := range results.AvailableProperties() {
+		v := GetPropertyValue(results, property)
+		if v.Reason == ValueError {
+			return v.Err
+		}
+		if v.HasValue() {
+			values[property] = v.Value()
+		}
+	}
+	return nil
+}
+
+// PropertyDiff is a property with different values for two device IDs. A
+// value is empty if the property has no value for that device ID.
+type PropertyDiff struct {
+	Property string
+	A, B     string
+}
+
+// ComponentDiff is the difference between the profiles of a component of two
+// device IDs.
+type ComponentDiff struct {
+	Component  string
+	A, B       uint32
+	Properties []PropertyDiff
+}
+
+// DiffDeviceIds compares two device IDs component by component and returns
+// the properties with different values, ordered by name. Components with the
+// same profile have no differences.
+func DiffDeviceIds(
+	manager *dd.ResourceManager,
+	a, b DeviceId) ([]ComponentDiff, error) {
+	if len(a.ProfileIds) != len(b.ProfileIds) {
+		return nil, fmt.Errorf(
+			"device ids '%s' and '%s' have different numbers of components",
+			a, b)
+	}
+	profilesA, err := DecomposeDeviceId(manager, a)
+	if err != nil {
+		return nil, err
+	}
+	profilesB, err := DecomposeDeviceId(manager, b)
+	if err != nil {
+		return nil, err
+	}
+
+	diffs := make([]ComponentDiff, len(profilesA))
+	for i := range profilesA {
+		pa, pb := profilesA[i], profilesB[i]
+		diffs[i] = ComponentDiff{Component: pa.Component, A: pa.ProfileId, B: pb.ProfileId}
+		// A profile ID of 0 has no values to name the component from
+		if pa.ProfileId == 0 {
+			diffs[i].Component = pb.Component
+		}
+		properties := make(map[string]bool)
+		for p := range pa.Values {
+			properties[p] = true
+		}
+		for p := range pb.Values {
+			properties[p] = true
+		}
+		for p := range properties {
+			if pa.Values[p] != pb.Values[p] {
+				diffs[i].Properties = append(diffs[i].Properties,
+					PropertyDiff{p, pa.Values[p], pb.Values[p]})
+			}
+		}
+		sort.Slice(diffs[i].Properties, func(x, y int) bool {
+			return diffs[i].Properties[x].Property < diffs[i].Properties[y].Property
+		})
+	}
+	return diffs, nil
+}
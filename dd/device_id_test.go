@@ -0,0 +1,143 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package dd_example
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/51Degrees/device-detection-examples-go/v4/dd/internal/testutil"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+// Test that device IDs are split into profile IDs and back.
+func TestParseDeviceId(t *testing.T) {
+	id, err := ParseDeviceId("12280-101474-102327-18092")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []uint32{12280, 101474, 102327, 18092}; !reflect.DeepEqual(id.ProfileIds, expected) {
+		t.Errorf("Expected profile ids %v, but got %v", expected, id.ProfileIds)
+	}
+	platform, err := id.Component(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := platform.String(); s != "0-101474-0-0" {
+		t.Errorf("Expected platform device id '0-101474-0-0', but got '%s'", s)
+	}
+	for _, index := range []int{-1, 4} {
+		if _, err := id.Component(index); err == nil {
+			t.Errorf("Expected an error for component index %d", index)
+		}
+	}
+	if s := id.String(); s != "12280-101474-102327-18092" {
+		t.Errorf("Expected '12280-101474-102327-18092', but got '%s'", s)
+	}
+
+	for _, invalid := range []string{"", "1--2", "a-1-2-3", "-1-0-0-0"} {
+		if _, err := ParseDeviceId(invalid); err == nil {
+			t.Errorf("Expected '%s' not to be a valid device id", invalid)
+		}
+	}
+}
+
+// Test that components are named from the properties of their profile, and
+// from their index if none identify it.
+func TestComponentName(t *testing.T) {
+	testData := []struct {
+		values   map[string]string
+		expected string
+	}{
+		{map[string]string{"HardwareVendor": "Apple", "IsMobile": "True"}, "Hardware"},
+		{map[string]string{"PlatformName": "iOS"}, "Platform"},
+		{map[string]string{"BrowserName": "Mobile Safari"}, "Browser"},
+		{map[string]string{"IsCrawler": "False"}, "Crawler"},
+		{map[string]string{"Other": "Value"}, "Component 1"},
+		{map[string]string{}, "Component 1"},
+	}
+	for _, data := range testData {
+		if actual := componentName(1, data.values); actual != data.expected {
+			t.Errorf("Expected '%s' for %v, but got '%s'",
+				data.expected, data.values, actual)
+		}
+	}
+}
+
+// Test that a detected device ID decomposes into profiles whose values are
+// those of the full detection, and differs from another only where expected.
+func TestDecomposeDeviceId(t *testing.T) {
+	filePath := testutil.DataFilePathOrSkip(t)
+	manager := dd.NewResourceManager()
+	config := dd.NewConfigHash(dd.Balanced)
+	if err := dd.InitManagerFromFile(manager, *config, "", filePath); err != nil {
+		t.Fatalf("Failed to initialize resource manager. %v", err)
+	}
+	defer manager.Free()
+
+	results := dd.NewResultsHash(manager, 1, 0)
+	defer results.Free()
+	if err := results.MatchUserAgent(iPhoneUA); err != nil {
+		t.Fatal(err)
+	}
+	s, err := results.DeviceId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := ParseDeviceId(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profiles, err := DecomposeDeviceId(manager, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range profiles {
+		if p.ProfileId != 0 && strings.HasPrefix(p.Component, "Component") {
+			t.Errorf("Expected component of profile %d to be named", p.ProfileId)
+		}
+		for property, value := range p.Values {
+			expected := GetPropertyValue(results, property).Value()
+			if value != expected {
+				t.Errorf("Expected %s %s to be '%s', but got '%s'",
+					p.Component, property, expected, value)
+			}
+		}
+	}
+
+	// Removing the browser profile only changes browser properties
+	other := DeviceId{append([]uint32(nil), id.ProfileIds...)}
+	other.ProfileIds[2] = 0
+	diffs, err := DiffDeviceIds(manager, id, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, d := range diffs {
+		if (len(d.Properties) > 0) != (i == 2 && id.ProfileIds[2] != 0) {
+			t.Errorf("Unexpected differences for %s: %v", d.Component, d.Properties)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package dd_example
+
+/*
+This file contains signed tokens which carry the device ID of a previous
+detection, so that a returning visitor can be resolved with MatchDeviceId
+rather than a full detection on the evidence of every request.
+
+A token is bound to a hash of the User-Agent it was issued for and expires
+after a maximum age. It is only issued if the detection satisfies the trust
+rules, so that low confidence detections are repeated rather than remembered.
+*/
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+// Minimum length of the key used to sign tokens
+const minTokenKeyLength = 16
+
+// Length of the truncated signature and User-Agent hash of a token
+const (
+	tokenSignatureLength = 16
+	tokenUserAgentLength = 8
+)
+
+// Errors returned by NewDeviceIdTokens and DeviceIdTokens.Verify
+var (
+	ErrTokenMalformed   = errors.New("device id token is malformed")
+	ErrTokenSignature   = errors.New("device id token signature is invalid")
+	ErrTokenExpired     = errors.New("device id token has expired")
+	ErrTokenUserAgent   = errors.New("device id token was issued for another User-Agent")
+	ErrTokenKeyTooShort = errors.New("device id token key must be at least 16 bytes")
+)
+
+// Defaults of the trust rules. Tokens are issued for detections which are
+// exact or close to exact matches, allowing for the small Drift and Difference
+// seen when matching common User-Agents with minor variations, e.g. in build
+// numbers. Detections which needed larger changes are repeated rather than
+// remembered. Tokens expire after a day so that updates to the data file are
+// seen by returning visitors.
+const (
+	DefaultTokenMaxAge        = 24 * time.Hour
+	DefaultTokenMaxDrift      = 1
+	DefaultTokenMaxDifference = 10
+)
+
+// DetectionQuality is the match metrics of a detection which the trust rules
+// are applied to.
+type DetectionQuality struct {
+	Method     dd.MatchMethod
+	Drift      int32
+	Difference int32
+}
+
+// QualityOf returns the match metrics of the last detection of the results.
+func QualityOf(results *dd.ResultsHash) DetectionQuality {
+	return DetectionQuality{
+		Method:     results.Method(),
+		Drift:      results.Drift(),
+		Difference: results.Difference(),
+	}
+}
+
+// TrustRules decide which detections are remembered in a token and for how
+// long. A zero MaxDrift or MaxDifference only trusts exact matches, so
+// DefaultTrustRules should be used as the starting point.
+type TrustRules struct {
+	// Maximum age of a token before a full detection is performed again
+	MaxAge time.Duration
+	// Maximum Drift and Difference of a detection to issue a token for
+	MaxDrift      int32
+	MaxDifference int32
+	// Match methods of a detection to issue a token for. Any method other
+	// than NONE if empty.
+	Methods []dd.MatchMethod
+	// Share of requests resolved from a token which are also detected from
+	// their evidence to compare the results, between 0 and 1
+	CompareRate float64
+}
+
+// DefaultTrustRules returns the rules with the default values.
+func DefaultTrustRules() TrustRules {
+	return TrustRules{
+		MaxAge:        DefaultTokenMaxAge,
+		MaxDrift:      DefaultTokenMaxDrift,
+		MaxDifference: DefaultTokenMaxDifference,
+	}
+}
+
+// trusts returns true if a detection satisfies the rules.
+func (r TrustRules) trusts(q DetectionQuality) bool {
+	if q.Drift > r.MaxDrift || q.Difference > r.MaxDifference {
+		return false
+	}
+	if len(r.Methods) == 0 {
+		return q.Method != dd.None
+	}
+	for _, m := range r.Methods {
+		if m == q.Method {
+			return true
+		}
+	}
+	return false
+}
+
+// DeviceIdMetrics counts how requests were resolved, to compare detection from
+// a device id with detection from the evidence.
+type DeviceIdMetrics struct {
+	// Tokens issued, and detections which did not satisfy the trust rules
+	Issued    uint64 `json:"issued"`
+	Untrusted uint64 `json:"untrusted"`
+	// Tokens accepted, and rejected for each reason
+	Accepted          uint64 `json:"accepted"`
+	Malformed         uint64 `json:"malformed"`
+	BadSignature      uint64 `json:"badSignature"`
+	Expired           uint64 `json:"expired"`
+	UserAgentMismatch uint64 `json:"userAgentMismatch"`
+	// Detections and their total duration for each path
+	DeviceIdDetections uint64        `json:"deviceIdDetections"`
+	DeviceIdDuration   time.Duration `json:"deviceIdDurationNs"`
+	EvidenceDetections uint64        `json:"evidenceDetections"`
+	EvidenceDuration   time.Duration `json:"evidenceDurationNs"`
+	// Requests detected by both paths, and those with the same results
+	Compared uint64 `json:"compared"`
+	Agreed   uint64 `json:"agreed"`
+}
+
+// DeviceIdTokens issues and verifies device id tokens, and records metrics of
+// their use. It is safe for concurrent use.
+type DeviceIdTokens struct {
+	Rules TrustRules
+	key   []byte
+	now   func() time.Time
+
+	mutex   sync.Mutex
+	rand    *rand.Rand
+	metrics DeviceIdMetrics
+}
+
+// NewDeviceIdTokens returns tokens signed with a secret key applying the trust
+// rules given.
+func NewDeviceIdTokens(key []byte, rules TrustRules) (*DeviceIdTokens, error) {
+	if len(key) < minTokenKeyLength {
+		return nil, ErrTokenKeyTooShort
+	}
+	return &DeviceIdTokens{
+		Rules: rules,
+		key:   append([]byte(nil), key...),
+		now:   time.Now,
+		rand:  rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
+}
+
+// Issue returns a token for the device id of a detection on the User-Agent
+// given, or false if the detection does not satisfy the trust rules.
+func (t *DeviceIdTokens) Issue(
+	deviceId string,
+	userAgent string,
+	quality DetectionQuality) (string, bool) {
+	if !t.Rules.trusts(quality) {
+		t.count(func(m *DeviceIdMetrics) { m.Untrusted++ })
+		return "", false
+	}
+	payload := strings.Join([]string{
+		deviceId,
+		strconv.FormatInt(t.now().Unix(), 36),
+		userAgentHash(userAgent),
+	}, "|")
+	t.count(func(m *DeviceIdMetrics) { m.Issued++ })
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		t.sign(payload), true
+}
+
+// Verify returns the device id of a token if it is signed with the key, has
+// not expired and was issued for the User-Agent given.
+func (t *DeviceIdTokens) Verify(token, userAgent string) (string, error) {
+	deviceId, err := t.verify(token, userAgent)
+	t.count(func(m *DeviceIdMetrics) {
+		switch err {
+		case nil:
+			m.Accepted++
+		case ErrTokenSignature:
+			m.BadSignature++
+		case ErrTokenExpired:
+			m.Expired++
+		case ErrTokenUserAgent:
+			m.UserAgentMismatch++
+		default:
+			m.Malformed++
+		}
+	})
+	return deviceId, err
+}
+
+func (t *DeviceIdTokens) verify(token, userAgent string) (string, error) {
+	encoded, signature, found := strings.Cut(token, ".")
+	if !found {
+		return "", ErrTokenMalformed
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrTokenMalformed
+	}
+	// Compare signatures before the payload is trusted
+	if !hmac.Equal([]byte(signature), []byte(t.sign(string(payload)))) {
+		return "", ErrTokenSignature
+	}
+	parts := strings.Split(string(payload), "|")
+	if len(parts) != 3 {
+		return "", ErrTokenMalformed
+	}
+	issued, err := strconv.ParseInt(parts[1], 36, 64)
+	if err != nil {
+		return "", ErrTokenMalformed
+	}
+	if t.Rules.MaxAge > 0 &&
+		t.now().Sub(time.Unix(issued, 0)) > t.Rules.MaxAge {
+		return "", ErrTokenExpired
+	}
+	if parts[2] != userAgentHash(userAgent) {
+		return "", ErrTokenUserAgent
+	}
+	return parts[0], nil
+}
+
+// ShouldCompare returns true if a request resolved from a token should also be
+// detected from its evidence, according to the compare rate.
+func (t *DeviceIdTokens) ShouldCompare() bool {
+	if t.Rules.CompareRate <= 0 {
+		return false
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.rand.Float64() < t.Rules.CompareRate
+}
+
+// ObserveDetection records the duration of a detection from a device id or
+// from the evidence.
+func (t *DeviceIdTokens) ObserveDetection(byDeviceId bool, d time.Duration) {
+	t.count(func(m *DeviceIdMetrics) {
+		if byDeviceId {
+			m.DeviceIdDetections++
+			m.DeviceIdDuration += d
+		} else {
+			m.EvidenceDetections++
+			m.EvidenceDuration += d
+		}
+	})
+}
+
+// ObserveComparison records whether the results from a device id and from the
+// evidence of the same request were the same.
+func (t *DeviceIdTokens) ObserveComparison(agreed bool) {
+	t.count(func(m *DeviceIdMetrics) {
+		m.Compared++
+		if agreed {
+			m.Agreed++
+		}
+	})
+}
+
+// Metrics returns a copy of the metrics recorded so far.
+func (t *DeviceIdTokens) Metrics() DeviceIdMetrics {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.metrics
+}
+
+func (t *DeviceIdTokens) count(fn func(m *DeviceIdMetrics)) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	fn(&t.metrics)
+}
+
+// sign returns the truncated HMAC of a payload.
+func (t *DeviceIdTokens) sign(payload string) string {
+	mac := hmac.New(sha256.New, t.key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(
+		mac.Sum(nil)[:tokenSignatureLength])
+}
+
+// userAgentHash returns a short hash of a User-Agent. The User-Agent itself is
+// not stored in the token to keep it small.
+func userAgentHash(userAgent string) string {
+	h := sha256.Sum256([]byte(userAgent))
+	return base64.RawURLEncoding.EncodeToString(h[:tokenUserAgentLength])
+}
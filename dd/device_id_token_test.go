@@ -0,0 +1,141 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package dd_example
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+const tokenTestKey = "0123456789abcdef"
+
+// Test that tokens are only accepted for the User-Agent they were issued for,
+// with a valid signature and before they expire.
+func TestDeviceIdTokens(t *testing.T) {
+	tokens, err := NewDeviceIdTokens(
+		[]byte(tokenTestKey),
+		TrustRules{MaxAge: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	tokens.now = func() time.Time { return now }
+
+	const deviceId = "12280-48866-24305-0"
+	quality := DetectionQuality{Method: dd.Performance}
+	token, ok := tokens.Issue(deviceId, iPhoneUA, quality)
+	if !ok {
+		t.Fatal("Expected a token to be issued")
+	}
+
+	// Change a single character of the signature
+	tampered := []byte(token)
+	if tampered[len(tampered)-1] == 'A' {
+		tampered[len(tampered)-1] = 'B'
+	} else {
+		tampered[len(tampered)-1] = 'A'
+	}
+	otherKey, _ := NewDeviceIdTokens(
+		[]byte(strings.ToUpper(tokenTestKey)),
+		TrustRules{MaxAge: time.Hour})
+
+	testData := []struct {
+		name     string
+		tokens   *DeviceIdTokens
+		token    string
+		ua       string
+		elapsed  time.Duration
+		expected error
+	}{
+		{"valid", tokens, token, iPhoneUA, 0, nil},
+		{"other User-Agent", tokens, token, "curl/7.80.0", 0, ErrTokenUserAgent},
+		{"expired", tokens, token, iPhoneUA, 2 * time.Hour, ErrTokenExpired},
+		{"tampered", tokens, string(tampered), iPhoneUA, 0, ErrTokenSignature},
+		{"other key", otherKey, token, iPhoneUA, 0, ErrTokenSignature},
+		{"malformed", tokens, "not a token", iPhoneUA, 0, ErrTokenMalformed},
+	}
+	for _, data := range testData {
+		data.tokens.now = func() time.Time { return now.Add(data.elapsed) }
+		actual, err := data.tokens.Verify(data.token, data.ua)
+		if err != data.expected {
+			t.Errorf("%s: expected error '%v', but got '%v'", data.name, data.expected, err)
+		} else if err == nil && actual != deviceId {
+			t.Errorf("%s: expected device id '%s', but got '%s'", data.name, deviceId, actual)
+		}
+	}
+
+	m := tokens.Metrics()
+	if m.Issued != 1 || m.Accepted != 1 || m.UserAgentMismatch != 1 ||
+		m.Expired != 1 || m.BadSignature != 1 || m.Malformed != 1 {
+		t.Errorf("Unexpected metrics %+v", m)
+	}
+}
+
+// Test that tokens are only issued for detections satisfying the trust rules.
+func TestTrustRules(t *testing.T) {
+	rules := TrustRules{
+		MaxDrift:      1,
+		MaxDifference: 10,
+		Methods:       []dd.MatchMethod{dd.Performance, dd.Combined},
+	}
+	testData := []struct {
+		quality  DetectionQuality
+		expected bool
+	}{
+		{DetectionQuality{dd.Performance, 0, 0}, true},
+		{DetectionQuality{dd.Combined, 1, 10}, true},
+		{DetectionQuality{dd.Predictive, 0, 0}, false},
+		{DetectionQuality{dd.Performance, 2, 0}, false},
+		{DetectionQuality{dd.Performance, 0, 11}, false},
+	}
+	for _, data := range testData {
+		if actual := rules.trusts(data.quality); actual != data.expected {
+			t.Errorf("Expected %+v to be trusted %v", data.quality, data.expected)
+		}
+	}
+
+	// Any method but NONE is trusted by default
+	if (TrustRules{}).trusts(DetectionQuality{Method: dd.None}) {
+		t.Error("Expected NONE not to be trusted by default")
+	}
+
+	// The default rules trust close matches but not distant ones
+	defaults := DefaultTrustRules()
+	if defaults.MaxAge <= 0 || defaults.MaxDrift <= 0 ||
+		defaults.MaxDifference <= 0 {
+		t.Errorf("Expected non-zero default rules, but got %+v", defaults)
+	}
+	if !defaults.trusts(DetectionQuality{dd.Performance, 1, 1}) {
+		t.Error("Expected a close match to be trusted by default")
+	}
+	if defaults.trusts(DetectionQuality{dd.Predictive, 0, 100}) {
+		t.Error("Expected a distant match not to be trusted by default")
+	}
+
+	if _, err := NewDeviceIdTokens([]byte("short"), rules); err != ErrTokenKeyTooShort {
+		t.Errorf("Expected error '%v', but got '%v'", ErrTokenKeyTooShort, err)
+	}
+}
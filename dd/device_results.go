@@ -0,0 +1,261 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package dd_example
+
+/*
+This file contains a typed layer over the results of a detection, so that
+property values can be used as bool, int, float64, []string or Version values
+rather than parsed from strings by every caller.
+
+Constants and accessors for individual properties, e.g. PropertyIsMobile and
+IsMobile(), are generated into properties_gen.go by gen_properties.
+*/
+
+//go:generate go run ./gen_properties -o properties_gen.go -properties IsMobile,ScreenPixelsWidth,ScreenPixelsHeight,HardwareVendor,HardwareName,HardwareModel,DeviceType,PlatformVendor,PlatformName,PlatformVersion,BrowserVendor,BrowserName,BrowserVersion
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+// Separator used when getting the values of a property as a list. It is a
+// control character so it cannot appear in a value.
+const valuesSeparator = "\x1f"
+
+// Values the engine returns for some properties with bool, int, float64 or
+// version values when it cannot determine one, rather than reporting no value.
+var unknownValues = map[string]bool{
+	"":        true,
+	"Unknown": true,
+	"N/A":     true,
+}
+
+// IsUnknownValue returns true if the value is one the engine returns when it
+// cannot determine the value of a property. The getters of DeviceResults
+// return a NoValueError for these values.
+func IsUnknownValue(value string) bool {
+	return unknownValues[value]
+}
+
+// NoValueError is returned by the getters of DeviceResults when a property
+// does not have a value for the evidence of the last detection.
+type NoValueError struct {
+	Property string // Name of the property
+	Reason   string // Reason given by the engine
+}
+
+func (e *NoValueError) Error() string {
+	return fmt.Sprintf("property '%s' has no value: %s", e.Property, e.Reason)
+}
+
+// PropertyNotAvailableError is returned by the getters of DeviceResults when a
+// property is not in the data file or was not required when the resource
+// manager was initialised.
+type PropertyNotAvailableError struct {
+	Property string // Name of the property
+}
+
+func (e *PropertyNotAvailableError) Error() string {
+	return fmt.Sprintf("property '%s' is not available", e.Property)
+}
+
+// ParseError is returned by the getters of DeviceResults when the value of a
+// property cannot be converted to the requested type.
+type ParseError struct {
+	Property string // Name of the property
+	Value    string // Value which could not be converted
+	Type     string // Type the value was converted to
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("value '%s' of property '%s' is not a valid %s",
+		e.Value, e.Property, e.Type)
+}
+
+// DeviceResults wraps the results of a detection to return property values
+// as Go types.
+type DeviceResults struct {
+	Results *dd.ResultsHash
+	// Gets the value of a property instead of Results if set, which allows
+	// the getters to be tested without a data file
+	propertyValue func(property string) PropertyValue
+}
+
+// NewDeviceResults returns typed access to the values of the results of a
+// detection. The results must outlive the returned object.
+func NewDeviceResults(results *dd.ResultsHash) *DeviceResults {
+	return &DeviceResults{Results: results}
+}
+
+// Strings returns all values of a property.
+func (d *DeviceResults) Strings(property string) ([]string, error) {
+	var v PropertyValue
+	if d.propertyValue != nil {
+		v = d.propertyValue(property)
+	} else {
+		v = GetPropertyValue(d.Results, property)
+	}
+	switch v.Reason {
+	case ValueFound:
+		return v.Values, nil
+	case ValueNotLoaded:
+		return nil, &PropertyNotAvailableError{property}
+	case ValueError:
+		return nil, v.Err
+	}
+	return nil, &NoValueError{property, v.Message}
+}
+
+// String returns the value of a property. If a property has more than one
+// value they are separated by commas.
+func (d *DeviceResults) String(property string) (string, error) {
+	values, err := d.Strings(property)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(values, ","), nil
+}
+
+// knownValue returns the value of a property, or a NoValueError if it is one
+// of the values which mean it is unknown.
+func (d *DeviceResults) knownValue(property string) (string, error) {
+	value, err := d.String(property)
+	if err != nil {
+		return "", err
+	}
+	if IsUnknownValue(value) {
+		return "", &NoValueError{property, fmt.Sprintf("the value is '%s'", value)}
+	}
+	return value, nil
+}
+
+// Bool returns the value of a property with "True" or "False" values.
+func (d *DeviceResults) Bool(property string) (bool, error) {
+	value, err := d.knownValue(property)
+	if err != nil {
+		return false, err
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, &ParseError{property, value, "bool"}
+	}
+	return b, nil
+}
+
+// Int returns the value of a property with integer values.
+func (d *DeviceResults) Int(property string) (int, error) {
+	value, err := d.knownValue(property)
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, &ParseError{property, value, "int"}
+	}
+	return i, nil
+}
+
+// Float64 returns the value of a property with decimal values.
+func (d *DeviceResults) Float64(property string) (float64, error) {
+	value, err := d.knownValue(property)
+	if err != nil {
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, &ParseError{property, value, "float64"}
+	}
+	return f, nil
+}
+
+// Version returns the value of a property with version number values.
+func (d *DeviceResults) Version(property string) (Version, error) {
+	value, err := d.knownValue(property)
+	if err != nil {
+		return Version{}, err
+	}
+	v, err := ParseVersion(value)
+	if err != nil {
+		return Version{}, &ParseError{property, value, "version"}
+	}
+	return v, nil
+}
+
+// BrowserAtLeast returns true if the browser detected is the one named and
+// its version is equal to or higher than the version given, e.g.
+// BrowserAtLeast("Chrome", "120").
+func (d *DeviceResults) BrowserAtLeast(name, version string) (bool, error) {
+	return d.BrowserMatches(name, ">="+version)
+}
+
+// BrowserMatches returns true if the browser detected is the one named and
+// its version is within the range given, e.g.
+// BrowserMatches("Chrome", ">=120 <130"). See ParseVersionRange.
+func (d *DeviceResults) BrowserMatches(name, versionRange string) (bool, error) {
+	return d.nameAndVersionMatch(
+		PropertyBrowserName, PropertyBrowserVersion, name, versionRange)
+}
+
+// PlatformAtLeast returns true if the platform detected is the one named and
+// its version is equal to or higher than the version given.
+func (d *DeviceResults) PlatformAtLeast(name, version string) (bool, error) {
+	return d.PlatformMatches(name, ">="+version)
+}
+
+// PlatformMatches returns true if the platform detected is the one named and
+// its version is within the range given, e.g. PlatformMatches("iOS", "<17.2").
+// The version is the one detected, which for some platforms is not the one
+// in the Sec-CH-UA-Platform-Version header, e.g. Windows 11 sends "14.0.0".
+func (d *DeviceResults) PlatformMatches(name, versionRange string) (bool, error) {
+	return d.nameAndVersionMatch(
+		PropertyPlatformName, PropertyPlatformVersion, name, versionRange)
+}
+
+// nameAndVersionMatch compares the name, ignoring case, and the version of a
+// browser or platform. A name or version without a value does not match.
+func (d *DeviceResults) nameAndVersionMatch(
+	nameProperty, versionProperty, name, versionRange string) (bool, error) {
+	r, err := ParseVersionRange(versionRange)
+	if err != nil {
+		return false, err
+	}
+	actualName, err := d.String(nameProperty)
+	if _, ok := err.(*NoValueError); ok {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if !strings.EqualFold(actualName, name) {
+		return false, nil
+	}
+	version, err := d.Version(versionProperty)
+	if _, ok := err.(*NoValueError); ok {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return r.Contains(version), nil
+}
@@ -0,0 +1,134 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package dd_example
+
+import (
+	"errors"
+	"testing"
+)
+
+// newTestDeviceResults returns device results which get the values given
+// rather than the results of a detection.
+func newTestDeviceResults(values map[string]string) *DeviceResults {
+	return &DeviceResults{propertyValue: func(property string) PropertyValue {
+		v := PropertyValue{Property: property}
+		if value, ok := values[property]; ok {
+			v.Values = []string{value}
+		} else {
+			v.Reason = ValueNoValue
+			v.Message = "No value"
+		}
+		return v
+	}}
+}
+
+// Test that bool, int, float64 and version values are parsed, that the values the
+// engine returns when it cannot determine one are no value, and that other
+// values cannot be parsed.
+func TestDeviceResultsTypes(t *testing.T) {
+	d := newTestDeviceResults(map[string]string{
+		"True":     "True",
+		"False":    "False",
+		"Int":      "1080",
+		"Negative": "-2",
+		"Float":    "6.1",
+		"Version":  "15.1",
+		"Unknown":  "Unknown",
+		"NA":       "N/A",
+		"Garbage":  "abc",
+	})
+
+	testData := []struct {
+		property string
+		get      func(string) (interface{}, error)
+		expected interface{}
+	}{
+		{"True", func(p string) (interface{}, error) { return d.Bool(p) }, true},
+		{"False", func(p string) (interface{}, error) { return d.Bool(p) }, false},
+		{"Int", func(p string) (interface{}, error) { return d.Int(p) }, 1080},
+		{"Negative", func(p string) (interface{}, error) { return d.Int(p) }, -2},
+		{"Int", func(p string) (interface{}, error) { return d.Float64(p) }, 1080.0},
+		{"Float", func(p string) (interface{}, error) { return d.Float64(p) }, 6.1},
+		{"Version", func(p string) (interface{}, error) {
+			v, err := d.Version(p)
+			return v.String(), err
+		}, "15.1"},
+	}
+	for _, data := range testData {
+		actual, err := data.get(data.property)
+		if err != nil {
+			t.Errorf("Unexpected error for '%s'. %v", data.property, err)
+		} else if actual != data.expected {
+			t.Errorf("Expected '%v' for '%s', but got '%v'",
+				data.expected, data.property, actual)
+		}
+	}
+
+	getters := map[string]func(string) error{
+		"bool":    func(p string) error { _, err := d.Bool(p); return err },
+		"int":     func(p string) error { _, err := d.Int(p); return err },
+		"float64": func(p string) error { _, err := d.Float64(p); return err },
+		"version": func(p string) error { _, err := d.Version(p); return err },
+	}
+	for name, get := range getters {
+		for _, property := range []string{"Unknown", "NA", "Missing"} {
+			var noValue *NoValueError
+			if err := get(property); !errors.As(err, &noValue) {
+				t.Errorf("Expected %s '%s' to have no value, but got '%v'",
+					name, property, err)
+			}
+		}
+		var parseErr *ParseError
+		if err := get("Garbage"); !errors.As(err, &parseErr) {
+			t.Errorf("Expected %s 'Garbage' to be a parse error, but got '%v'",
+				name, err)
+		}
+	}
+	var parseErr *ParseError
+	if _, err := d.Int("Float"); !errors.As(err, &parseErr) {
+		t.Errorf("Expected int 'Float' to be a parse error, but got '%v'", err)
+	}
+}
+
+// Test that the generated accessors of properties which can be "Unknown"
+// return no value rather than a parse error.
+func TestGeneratedAccessorsUnknown(t *testing.T) {
+	d := newTestDeviceResults(map[string]string{
+		PropertyScreenPixelsWidth:  "Unknown",
+		PropertyScreenPixelsHeight: "1920",
+		PropertyIsMobile:           "Unknown",
+	})
+
+	var noValue *NoValueError
+	if _, err := d.ScreenPixelsWidth(); !errors.As(err, &noValue) {
+		t.Errorf("Expected ScreenPixelsWidth to have no value, but got '%v'",
+			err)
+	}
+	if _, err := d.IsMobile(); !errors.As(err, &noValue) {
+		t.Errorf("Expected IsMobile to have no value, but got '%v'", err)
+	}
+	if height, err := d.ScreenPixelsHeight(); err != nil || height != 1920 {
+		t.Errorf("Expected ScreenPixelsHeight '1920', but got '%d' '%v'",
+			height, err)
+	}
+}
@@ -0,0 +1,464 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package main
+
+/*
+This example illustrates how to evaluate the accuracy of detection against
+records labeled with the true values of properties, such as devices tested in
+a QA lab.
+
+The labeled records are read from either:
+  - an Evidence Records YAML file where the true value of a property is given
+    by a `meta.expected.[property]` key, as in the fixtures of the `dd`
+    package, or
+  - a CSV file with a header row, where a `User-Agent` column or columns named
+    as evidence keys, e.g. `header.sec-ch-ua`, give the evidence and every
+    other column gives the true value of the property it is named after.
+
+For each property the accuracy, the precision and recall of each value and a
+confusion matrix of true against detected values are reported, followed by the
+misclassified records. Records deduplicated by evidence_corpus with `-count`
+are weighted by the number of times they occurred.
+
+To run this example, perform the following command:
+```
+go run evaluate_accuracy.go -e labeled.csv
+```
+If no file is given, the fixtures embedded in the `dd` package are used.
+*/
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	dd_example "github.com/51Degrees/device-detection-examples-go/v4/dd"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+type options struct {
+	DataFilePath  string
+	LabeledPath   string
+	Properties    string
+	Misclassified int
+	showHelp      bool
+}
+
+func parseOptions() options {
+	o := options{}
+
+	flag.StringVar(&o.DataFilePath, "data-file", "../"+dd_example.LiteDataFile, "Path to a 51Degrees Hash data file")
+	flag.StringVar(&o.DataFilePath, "d", o.DataFilePath, "Alias for -data-file")
+
+	flag.StringVar(&o.LabeledPath, "evidence-file", "", "Path to a labeled Evidence Records YAML or CSV file. The embedded fixtures are used if not given")
+	flag.StringVar(&o.LabeledPath, "e", o.LabeledPath, "Alias for -evidence-file")
+
+	flag.StringVar(&o.Properties, "properties", strings.Join([]string{
+		dd_example.PropertyDeviceType,
+		dd_example.PropertyPlatformName,
+		dd_example.PropertyBrowserName,
+		dd_example.PropertyIsMobile,
+	}, ","), "Comma separated list of properties to evaluate")
+
+	flag.IntVar(&o.Misclassified, "misclassified", 20, "Number of misclassified records to list")
+
+	flag.BoolVar(&o.showHelp, "help", false, "Print help")
+	flag.BoolVar(&o.showHelp, "h", o.showHelp, "Alias for -help")
+
+	flag.Parse()
+	return o
+}
+
+// readLabeledCsv reads labeled records from a CSV file with a header row.
+func readLabeledCsv(r io.Reader) ([]dd_example.Fixture, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no header row")
+	}
+	keys := make([]string, len(rows[0]))
+	for i, column := range rows[0] {
+		column = strings.TrimSpace(column)
+		switch {
+		case strings.EqualFold(column, "User-Agent"):
+			keys[i] = "header.user-agent"
+		case strings.Contains(column, "."):
+			keys[i] = column
+		default:
+			keys[i] = dd_example.ExpectedKey(column)
+		}
+	}
+	fixtures := make([]dd_example.Fixture, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		record := make(map[string]string)
+		for i, value := range row {
+			if value != "" {
+				record[keys[i]] = value
+			}
+		}
+		fixtures = append(fixtures, dd_example.NewFixture(record))
+	}
+	return fixtures, nil
+}
+
+// readLabeled reads the labeled records of a file, or the embedded fixtures
+// if no file is given.
+func readLabeled(path string) ([]dd_example.Fixture, error) {
+	if path == "" {
+		return dd_example.Fixtures()
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return readLabeledCsv(file)
+	}
+	return dd_example.ReadFixtures(file)
+}
+
+// confusion counts the detected values of a property against the true values.
+type confusion struct {
+	property string
+	// Counts keyed by true value then detected value
+	counts  map[string]map[string]uint64
+	total   uint64
+	correct uint64
+}
+
+func newConfusion(property string) *confusion {
+	return &confusion{
+		property: property,
+		counts:   make(map[string]map[string]uint64),
+	}
+}
+
+// detectedLabel returns the value detected for a property, or the reason it
+// has none in angle brackets so that it never equals a true value.
+func detectedLabel(value dd_example.PropertyValue) string {
+	if !value.HasValue() {
+		return "<" + value.Reason.String() + ">"
+	}
+	return value.Value()
+}
+
+// add counts a detection, returning true if it is correct. Values which only
+// differ in case are the same. A property without a value is never correct.
+func (c *confusion) add(
+	expected string,
+	value dd_example.PropertyValue,
+	weight uint64) bool {
+	detected := detectedLabel(value)
+	ok := value.HasValue() && strings.EqualFold(expected, value.Value())
+	if ok {
+		detected = expected
+		c.correct += weight
+	}
+	row, found := c.counts[expected]
+	if !found {
+		row = make(map[string]uint64)
+		c.counts[expected] = row
+	}
+	row[detected] += weight
+	c.total += weight
+	return ok
+}
+
+func (c *confusion) accuracy() float64 {
+	return share(c.correct, c.total)
+}
+
+// values returns the true and detected values, most common true values
+// first and then detected values which are never true.
+func (c *confusion) values() []string {
+	actual := make(map[string]uint64)
+	for expected, row := range c.counts {
+		for detected, n := range row {
+			actual[expected] += n
+			if _, ok := actual[detected]; !ok {
+				actual[detected] = 0
+			}
+		}
+	}
+	values := make([]string, 0, len(actual))
+	for v := range actual {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if actual[values[i]] != actual[values[j]] {
+			return actual[values[i]] > actual[values[j]]
+		}
+		return values[i] < values[j]
+	})
+	return values
+}
+
+// precisionRecall returns the share of detections of a value which are
+// correct, and the share of records with the true value which are detected
+// as it.
+func (c *confusion) precisionRecall(value string) (float64, float64) {
+	var detected, actual uint64
+	for expected, row := range c.counts {
+		detected += row[value]
+		if expected == value {
+			for _, n := range row {
+				actual += n
+			}
+		}
+	}
+	truePositive := c.counts[value][value]
+	return share(truePositive, detected), share(truePositive, actual)
+}
+
+// share returns n as a percentage of total.
+func share(n, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total) * 100
+}
+
+// misclassification is a record whose detected value of a property is not
+// the true value.
+type misclassification struct {
+	name      string
+	userAgent string
+	property  string
+	expected  string
+	detected  string
+	count     uint64
+}
+
+// report of the accuracy of the detection of labeled records.
+type report struct {
+	records        uint64
+	confusions     []*confusion
+	notLoaded      []string
+	misclassified  []misclassification
+	nMisclassified int
+	misses         uint64
+}
+
+func newReport(properties []string, nMisclassified int) *report {
+	r := &report{nMisclassified: nMisclassified}
+	for _, p := range properties {
+		r.confusions = append(r.confusions, newConfusion(p))
+	}
+	return r
+}
+
+// add counts the detected values of a labeled record.
+func (r *report) add(
+	f dd_example.Fixture,
+	detected map[string]dd_example.PropertyValue,
+	weight uint64) {
+	r.records += weight
+	for _, c := range r.confusions {
+		expected, ok := f.Expected[c.property]
+		if !ok {
+			continue
+		}
+		if c.add(expected, detected[c.property], weight) {
+			continue
+		}
+		r.misses += weight
+		if len(r.misclassified) < r.nMisclassified {
+			r.misclassified = append(r.misclassified, misclassification{
+				name:      f.Name,
+				userAgent: f.Record["header.user-agent"],
+				property:  c.property,
+				expected:  expected,
+				detected:  detectedLabel(detected[c.property]),
+				count:     weight,
+			})
+		}
+	}
+}
+
+// detect performs a detection on a record and returns the values of the
+// properties.
+func detect(
+	manager *dd.ResourceManager,
+	record map[string]string,
+	properties []string) map[string]dd_example.PropertyValue {
+	evidence := dd_example.ExtractEvidence(dd_example.ConvertEvidenceMap(record))
+	defer evidence.Free()
+	results := dd_example.NewTrackedResults(manager, uint32(evidence.Count()), 0)
+	defer results.Free()
+
+	if err := results.MatchEvidence(evidence.Evidence); err != nil {
+		dd_example.Fatalf("ERROR: Failed to perform detection. %v\n", err)
+	}
+	values := make(map[string]dd_example.PropertyValue)
+	for _, property := range properties {
+		values[property] = dd_example.GetPropertyValue(results.ResultsHash, property)
+	}
+	return values
+}
+
+// evaluate detects every labeled record. Properties which are not in the data
+// file are reported rather than counted as misclassified.
+func evaluate(
+	manager *dd.ResourceManager,
+	fixtures []dd_example.Fixture,
+	properties []string,
+	nMisclassified int) *report {
+	loaded := make([]string, 0, len(properties))
+	notLoaded := make([]string, 0)
+	for _, p := range properties {
+		if requiredPropertyIndex(manager, p) < 0 {
+			notLoaded = append(notLoaded, p)
+		} else {
+			loaded = append(loaded, p)
+		}
+	}
+	r := newReport(loaded, nMisclassified)
+	r.notLoaded = notLoaded
+	for _, f := range fixtures {
+		r.add(f, detect(manager, f.Record, loaded), dd_example.RecordCount(f.Record))
+	}
+	return r
+}
+
+// requiredPropertyIndex returns the index of a property in the results of
+// the manager, or -1 if it is not in the data file.
+func requiredPropertyIndex(manager *dd.ResourceManager, property string) int {
+	results := dd_example.NewTrackedResults(manager, 0, 0)
+	defer results.Free()
+	return results.RequiredPropertyIndexFromName(property)
+}
+
+// print writes the report in human readable form.
+func (r *report) print(w io.Writer) {
+	fmt.Fprintf(w, "Records: %d\n", r.records)
+	for _, p := range r.notLoaded {
+		fmt.Fprintf(w, "Property '%s' is not in the data file.\n", p)
+	}
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Property\tLabeled\tCorrect\tAccuracy\t")
+	for _, c := range r.confusions {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.2f%%\t\n",
+			c.property, c.total, c.correct, c.accuracy())
+	}
+	tw.Flush()
+
+	for _, c := range r.confusions {
+		if c.total == 0 {
+			continue
+		}
+		values := c.values()
+
+		fmt.Fprintf(w, "\n%s:\n", c.property)
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "Value\tPrecision\tRecall\t")
+		for _, v := range values {
+			precision, recall := c.precisionRecall(v)
+			fmt.Fprintf(tw, "%s\t%.2f%%\t%.2f%%\t\n", v, precision, recall)
+		}
+		tw.Flush()
+
+		fmt.Fprintf(w, "\n%s confusion matrix (rows are true values, "+
+			"columns are detected values):\n", c.property)
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintf(tw, "\t%s\t\n", strings.Join(values, "\t"))
+		for _, expected := range values {
+			row, ok := c.counts[expected]
+			if !ok {
+				continue
+			}
+			fmt.Fprintf(tw, "%s\t", expected)
+			for _, detected := range values {
+				fmt.Fprintf(tw, "%d\t", row[detected])
+			}
+			fmt.Fprintln(tw)
+		}
+		tw.Flush()
+	}
+
+	if r.misses > 0 {
+		var listed uint64
+		for _, m := range r.misclassified {
+			listed += m.count
+		}
+		fmt.Fprintf(w, "\nMisclassified (%d of %d):\n", listed, r.misses)
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "Name\tProperty\tExpected\tDetected\tCount\tUser-Agent\t")
+		for _, m := range r.misclassified {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t\n",
+				m.name, m.property, m.expected, m.detected, m.count, m.userAgent)
+		}
+		tw.Flush()
+	}
+}
+
+func main() {
+	defer dd_example.ReportLeaksAtExit()
+
+	o := parseOptions()
+	if o.showHelp {
+		flag.Usage()
+		return
+	}
+
+	properties := make([]string, 0)
+	for _, p := range strings.Split(o.Properties, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			properties = append(properties, p)
+		}
+	}
+
+	fixtures, err := readLabeled(o.LabeledPath)
+	if err != nil {
+		dd_example.Fatalf("ERROR: Failed to read labeled records. %v\n", err)
+	}
+
+	// Initialise manager
+	manager := dd_example.NewTrackedManager()
+	config := dd.NewConfigHash(dd.Balanced)
+	filePath := dd_example.GetFilePathByPath(o.DataFilePath)
+	err = dd.InitManagerFromFile(
+		manager.ResourceManager,
+		*config,
+		"",
+		filePath)
+	if err != nil {
+		dd_example.Fatalln(err)
+	}
+
+	// Make sure manager object will be freed after the function execution
+	defer manager.Free()
+
+	evaluate(manager.ResourceManager, fixtures, properties, o.Misclassified).print(os.Stdout)
+}
@@ -0,0 +1,151 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	dd_example "github.com/51Degrees/device-detection-examples-go/v4/dd"
+)
+
+// found returns a property value which was found by a detection.
+func found(value string) dd_example.PropertyValue {
+	return dd_example.PropertyValue{Values: []string{value}}
+}
+
+// Test accuracy, precision and recall against a known confusion matrix.
+func TestConfusion(t *testing.T) {
+	c := newConfusion("DeviceType")
+	c.add("Mobile", found("Mobile"), 6)
+	c.add("Mobile", found("Tablet"), 2)
+	c.add("Tablet", found("tablet"), 1)
+	c.add("Desktop", found("Mobile"), 1)
+
+	if accuracy := c.accuracy(); accuracy != 70 {
+		t.Errorf("Expected accuracy to be 70%%, but got %v", accuracy)
+	}
+	testData := []struct {
+		value     string
+		precision float64
+		recall    float64
+	}{
+		{"Mobile", 6.0 / 7 * 100, 75},
+		{"Tablet", 1.0 / 3 * 100, 100},
+		{"Desktop", 0, 0},
+	}
+	for _, data := range testData {
+		precision, recall := c.precisionRecall(data.value)
+		if math.Abs(precision-data.precision) > 1e-9 ||
+			math.Abs(recall-data.recall) > 1e-9 {
+			t.Errorf("Expected %s precision %v and recall %v, but got %v and %v",
+				data.value, data.precision, data.recall, precision, recall)
+		}
+	}
+	expected := "Mobile,Desktop,Tablet"
+	if values := strings.Join(c.values(), ","); values != expected {
+		t.Errorf("Expected values '%s', but got '%s'", expected, values)
+	}
+}
+
+// Test that a property without a value is misclassified, even when its label
+// is the same as the reason it has none.
+func TestConfusionNoValue(t *testing.T) {
+	c := newConfusion("DeviceType")
+	noValue := dd_example.PropertyValue{
+		Property: "DeviceType",
+		Reason:   dd_example.ValueNoValue,
+	}
+	if c.add("No value", noValue, 1) {
+		t.Error("Expected a property without a value to be misclassified")
+	}
+	if c.add("Mobile", noValue, 1) {
+		t.Error("Expected a property without a value to be misclassified")
+	}
+	if c.correct != 0 || c.counts["Mobile"]["<No value>"] != 1 {
+		t.Errorf("Expected no correct detections and 1 'Mobile' counted as "+
+			"'<No value>', but got %d and %v", c.correct, c.counts)
+	}
+	if precision, recall := c.precisionRecall("No value"); precision != 0 ||
+		recall != 0 {
+		t.Errorf("Expected 'No value' precision and recall of 0, but got %v "+
+			"and %v", precision, recall)
+	}
+}
+
+// Test that columns of a CSV file are read as evidence or expected values.
+func TestReadLabeledCsv(t *testing.T) {
+	fixtures, err := readLabeledCsv(strings.NewReader(
+		"User-Agent,header.sec-ch-ua-mobile,DeviceType,IsMobile\n" +
+			"Mozilla/5.0 (iPhone),?1,SmartPhone,True\n" +
+			"curl/7.80.0,,,False\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) != 2 {
+		t.Fatalf("Expected 2 records, but got %d", len(fixtures))
+	}
+	f := fixtures[0]
+	if f.Record["header.user-agent"] != "Mozilla/5.0 (iPhone)" ||
+		f.Record["header.sec-ch-ua-mobile"] != "?1" ||
+		f.Expected["DeviceType"] != "SmartPhone" ||
+		f.Expected["IsMobile"] != "True" {
+		t.Errorf("Unexpected record %v", f.Record)
+	}
+	if _, ok := fixtures[1].Expected["DeviceType"]; ok {
+		t.Errorf("Expected no DeviceType label for an empty value")
+	}
+}
+
+// Test that only labeled properties are counted, misclassified records are
+// listed up to the limit, and records are weighted by their count.
+func TestReport(t *testing.T) {
+	r := newReport([]string{"IsMobile", "DeviceType"}, 1)
+	for _, d := range []struct {
+		expected map[string]string
+		detected map[string]dd_example.PropertyValue
+		weight   uint64
+	}{
+		{map[string]string{"IsMobile": "True"}, map[string]dd_example.PropertyValue{"IsMobile": found("True"), "DeviceType": found("Mobile")}, 1},
+		{map[string]string{"IsMobile": "True"}, map[string]dd_example.PropertyValue{"IsMobile": found("False"), "DeviceType": found("Desktop")}, 3},
+		{map[string]string{"DeviceType": "Tablet"}, map[string]dd_example.PropertyValue{"IsMobile": found("True"), "DeviceType": found("Mobile")}, 1},
+	} {
+		r.add(dd_example.Fixture{Expected: d.expected}, d.detected, d.weight)
+	}
+
+	if r.records != 5 || r.confusions[0].total != 4 || r.confusions[1].total != 1 {
+		t.Errorf("Expected 5 records with 4 and 1 labels, but got %d with %d and %d",
+			r.records, r.confusions[0].total, r.confusions[1].total)
+	}
+	if r.misses != 4 || len(r.misclassified) != 1 || r.misclassified[0].count != 3 {
+		t.Errorf("Expected 4 misses with 1 of count 3 listed, but got %d with %v listed",
+			r.misses, r.misclassified)
+	}
+	var b strings.Builder
+	r.print(&b)
+	if !strings.Contains(b.String(), "Misclassified (3 of 4)") {
+		t.Errorf("Expected misclassified records to be listed, but got:\n%s",
+			b.String())
+	}
+}
@@ -0,0 +1,304 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package dd_example
+
+/*
+This file contains the evidence writer and the live evidence capture used by
+the web examples to build an evidence corpus from real traffic. Captured files
+are in the same format as the Evidence Records file so they can be replayed by
+the offline processing and performance examples.
+*/
+
+import (
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+	"gopkg.in/yaml.v3"
+)
+
+// Marker written at the start of each Evidence Record
+const documentStart = "---\n"
+
+// Marker written at the end of an Evidence Records file
+const documentEnd = "...\n"
+
+// EvidenceWriter writes Evidence Records as a stream of YAML documents in the
+// format of the Evidence Records file. Each record is a map of 'prefix.key'
+// to value.
+type EvidenceWriter struct {
+	w io.Writer
+}
+
+// NewEvidenceWriter creates a writer of Evidence Records to w.
+func NewEvidenceWriter(w io.Writer) *EvidenceWriter {
+	return &EvidenceWriter{w}
+}
+
+// MarshalEvidenceRecord returns a single Evidence Record as a YAML document.
+// Keys are written in sorted order so the same record always has the same
+// representation.
+func MarshalEvidenceRecord(record map[string]string) ([]byte, error) {
+	body, err := yaml.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(documentStart), body...), nil
+}
+
+// Write writes a single Evidence Record and returns the number of bytes
+// written.
+func (ew *EvidenceWriter) Write(record map[string]string) (int, error) {
+	doc, err := MarshalEvidenceRecord(record)
+	if err != nil {
+		return 0, err
+	}
+	return ew.w.Write(doc)
+}
+
+// Close writes the end of file marker. It does not close the underlying
+// writer.
+func (ew *EvidenceWriter) Close() error {
+	_, err := io.WriteString(ew.w, documentEnd)
+	return err
+}
+
+// CaptureOptions configures live evidence capture.
+type CaptureOptions struct {
+	// Path of the file evidence is captured to. Capture is disabled if empty.
+	FilePath string
+	// Fraction of requests to capture, between 0 and 1.
+	SampleRate float64
+	// Maximum size in bytes of a capture file before it is rotated. No limit
+	// is applied if 0.
+	MaxFileSize int64
+	// Whether records already written to the current file are skipped.
+	Deduplicate bool
+}
+
+// CaptureFlags registers the command line options for live evidence capture
+// and returns the options they will be parsed into. Must be called before
+// flag.Parse.
+func CaptureFlags() *CaptureOptions {
+	options := &CaptureOptions{}
+	flag.StringVar(&options.FilePath, "capture-file", "", "Path to a file to capture request evidence to. Capture is disabled if not set")
+	flag.Float64Var(&options.SampleRate, "capture-sample-rate", 1, "Fraction of requests to capture, between 0 and 1")
+	flag.Int64Var(&options.MaxFileSize, "capture-max-size", 10*1024*1024, "Maximum size in bytes of a capture file before it is rotated")
+	flag.BoolVar(&options.Deduplicate, "capture-dedup", true, "Skip records already captured to the current file")
+	return options
+}
+
+// EvidenceCapture writes the evidence of sampled requests to a rotating
+// Evidence Records file. It is safe for concurrent use by multiple handlers.
+type EvidenceCapture struct {
+	mu      sync.Mutex
+	options CaptureOptions
+	rnd     *rand.Rand
+	file    *os.File
+	writer  *EvidenceWriter
+	size    int64
+	seen    map[uint64]struct{}
+	rotated int
+}
+
+// NewEvidenceCapture creates the capture file and returns a capture writing
+// to it. An existing capture file, such as one left by a server which was
+// stopped, is rotated rather than replaced. Returns nil if no capture file is
+// configured.
+func NewEvidenceCapture(options CaptureOptions) (*EvidenceCapture, error) {
+	if options.FilePath == "" {
+		return nil, nil
+	}
+	if options.SampleRate < 0 || options.SampleRate > 1 {
+		return nil, fmt.Errorf(
+			"sample rate '%v' is not between 0 and 1", options.SampleRate)
+	}
+	c := &EvidenceCapture{
+		options: options,
+		rnd:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	if info, err := os.Stat(options.FilePath); err == nil && info.Size() > 0 {
+		if err := os.Rename(options.FilePath, c.rotatedPath()); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.open(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// open creates a new capture file, replacing any existing one.
+func (c *EvidenceCapture) open() error {
+	f, err := os.Create(c.options.FilePath)
+	if err != nil {
+		return err
+	}
+	c.file = f
+	c.writer = NewEvidenceWriter(f)
+	c.size = 0
+	c.seen = make(map[uint64]struct{})
+	return nil
+}
+
+// finish ends and closes the current capture file. The end marker is only
+// written if the file contains records, as a lone marker is not valid YAML.
+func (c *EvidenceCapture) finish() error {
+	if c.size > 0 {
+		if err := c.writer.Close(); err != nil {
+			c.file.Close()
+			return err
+		}
+	}
+	return c.file.Close()
+}
+
+// rotatedPath returns the next free path a full capture file can be moved
+// to. For "capture.yml" these are "capture.1.yml", "capture.2.yml" and so on.
+func (c *EvidenceCapture) rotatedPath() string {
+	ext := filepath.Ext(c.options.FilePath)
+	base := strings.TrimSuffix(c.options.FilePath, ext)
+	for {
+		c.rotated++
+		path := fmt.Sprintf("%s.%d%s", base, c.rotated, ext)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+	}
+}
+
+// rotate moves the current capture file aside and starts a new one. If the
+// current file cannot be finished it is closed, so the capture is closed too.
+func (c *EvidenceCapture) rotate() error {
+	if err := c.finish(); err != nil {
+		c.file = nil
+		c.writer = nil
+		return err
+	}
+	if err := os.Rename(c.options.FilePath, c.rotatedPath()); err != nil {
+		return err
+	}
+	return c.open()
+}
+
+// Capture writes an Evidence Record of 'prefix.key' to value if the request
+// is sampled. Keys are lower cased to match the Evidence Records file. Empty
+// records, and records already in the current file when deduplicating, are
+// skipped.
+func (c *EvidenceCapture) Capture(evidence map[string]string) error {
+	// Sample before doing any work for the record
+	if len(evidence) == 0 || !c.sample() {
+		return nil
+	}
+	return c.write(evidence)
+}
+
+// CaptureRequest writes an Evidence Record of the values of the required
+// evidence keys present in a http request if the request is sampled, as
+// returned by ExtractRequestEvidence, so that every web example captures the
+// same records.
+func (c *EvidenceCapture) CaptureRequest(
+	r *http.Request,
+	keys []dd.EvidenceKey,
+	clientIP func(r *http.Request) string) error {
+	if !c.sample() {
+		return nil
+	}
+	evidence := ExtractRequestEvidence(r, keys, clientIP)
+	if len(evidence) == 0 {
+		return nil
+	}
+	return c.write(EvidenceRecord(evidence))
+}
+
+// sample returns true if a request is to be captured.
+func (c *EvidenceCapture) sample() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rnd.Float64() < c.options.SampleRate
+}
+
+// write writes a record, with keys lower cased, unless it is a duplicate.
+func (c *EvidenceCapture) write(evidence map[string]string) error {
+	record := make(map[string]string, len(evidence))
+	for k, v := range evidence {
+		record[strings.ToLower(k)] = v
+	}
+	doc, err := MarshalEvidenceRecord(record)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file == nil {
+		return fmt.Errorf("evidence capture is closed")
+	}
+
+	h := fnv.New64a()
+	h.Write(doc)
+	key := h.Sum64()
+	if c.options.Deduplicate {
+		if _, ok := c.seen[key]; ok {
+			return nil
+		}
+	}
+
+	// Rotate before the record would take the file over its maximum size,
+	// unless the file is empty so a single large record is still captured.
+	if c.options.MaxFileSize > 0 && c.size > 0 &&
+		c.size+int64(len(doc))+int64(len(documentEnd)) > c.options.MaxFileSize {
+		if err := c.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := c.file.Write(doc)
+	c.size += int64(n)
+	if err != nil {
+		return err
+	}
+	c.seen[key] = struct{}{}
+	return nil
+}
+
+// Close ends the current capture file. Further records are rejected.
+func (c *EvidenceCapture) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file == nil {
+		return nil
+	}
+	err := c.finish()
+	c.file = nil
+	return err
+}
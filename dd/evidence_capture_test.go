@@ -0,0 +1,191 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package dd_example
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// readEvidenceRecords decodes all Evidence Records in a file.
+func readEvidenceRecords(t *testing.T, path string) []map[string]string {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var records []map[string]string
+	dec := yaml.NewDecoder(f)
+	for {
+		var doc map[string]string
+		if err := dec.Decode(&doc); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Failed to decode \"%s\". %v", path, err)
+		}
+		records = append(records, doc)
+	}
+	return records
+}
+
+// Test that captured evidence can be read back as Evidence Records, with
+// duplicates skipped and keys lower cased.
+func TestEvidenceCapture(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.yml")
+	c, err := NewEvidenceCapture(CaptureOptions{
+		FilePath:    path,
+		SampleRate:  1,
+		Deduplicate: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := []map[string]string{
+		{"header.User-Agent": "Mozilla/5.0 (Test)"},
+		{"header.User-Agent": "Mozilla/5.0 (Test)"},
+		{"header.User-Agent": "curl/7.80.0", "query.Sec-CH-UA": "\"Chromium\";v=\"124\""},
+		{},
+	}
+	for _, r := range records {
+		if err := c.Capture(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []map[string]string{
+		{"header.user-agent": "Mozilla/5.0 (Test)"},
+		{"header.user-agent": "curl/7.80.0", "query.sec-ch-ua": "\"Chromium\";v=\"124\""},
+	}
+	if actual := readEvidenceRecords(t, path); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected records '%v', but got '%v'", expected, actual)
+	}
+	if count := CountEvidenceFromFiles(path); count != uint64(len(expected)) {
+		t.Errorf("Expected '%d' records to be counted, but got '%d'",
+			len(expected), count)
+	}
+}
+
+// Test that capture files are rotated once they reach the maximum size and
+// that sampling can disable capture entirely.
+func TestEvidenceCaptureRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "capture.yml")
+	record := map[string]string{"header.user-agent": "Mozilla/5.0 (Test)"}
+	doc, err := MarshalEvidenceRecord(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Room for two records and the end marker in each file
+	c, err := NewEvidenceCapture(CaptureOptions{
+		FilePath:    path,
+		SampleRate:  1,
+		MaxFileSize: int64(2*len(doc) + len(documentEnd)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := c.Capture(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for file, count := range map[string]int{
+		"capture.1.yml": 2,
+		"capture.2.yml": 2,
+		"capture.yml":   1,
+	} {
+		if n := len(readEvidenceRecords(t, filepath.Join(dir, file))); n != count {
+			t.Errorf("Expected '%d' records in \"%s\", but got '%d'",
+				count, file, n)
+		}
+	}
+
+	// Nothing is captured with a zero sample rate
+	c, err = NewEvidenceCapture(CaptureOptions{FilePath: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Capture(record); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if n := len(readEvidenceRecords(t, path)); n != 0 {
+		t.Errorf("Expected no records with zero sample rate, but got '%d'", n)
+	}
+
+	// The file left by the previous capture is rotated, not replaced
+	if n := len(readEvidenceRecords(t, filepath.Join(dir, "capture.3.yml"))); n != 1 {
+		t.Errorf("Expected the existing file to be kept with '1' record, but got '%d'", n)
+	}
+
+	if _, err := NewEvidenceCapture(CaptureOptions{FilePath: path, SampleRate: 2}); err == nil {
+		t.Error("Expected an error for a sample rate above 1")
+	}
+}
+
+// Test that the capture is closed if the full file cannot be finished, rather
+// than writing to the file which was closed.
+func TestEvidenceCaptureRotationFailure(t *testing.T) {
+	record := map[string]string{"header.user-agent": "Mozilla/5.0 (Test)"}
+	c, err := NewEvidenceCapture(CaptureOptions{
+		FilePath:    filepath.Join(t.TempDir(), "capture.yml"),
+		SampleRate:  1,
+		MaxFileSize: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Capture(record); err != nil {
+		t.Fatal(err)
+	}
+
+	// Writing the end marker fails as the file is already closed
+	c.file.Close()
+	if err := c.Capture(record); err == nil {
+		t.Fatal("Expected the rotation to fail")
+	}
+	if c.file != nil {
+		t.Error("Expected the closed file to be released")
+	}
+	if err := c.Capture(record); err == nil ||
+		!strings.Contains(err.Error(), "closed") {
+		t.Errorf("Expected the capture to be closed, but got %v", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("Expected closing again to succeed, but got %v", err)
+	}
+}
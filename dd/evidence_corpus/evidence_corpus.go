@@ -0,0 +1,448 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package main
+
+/*
+This example illustrates how large Evidence Records files can be reduced to a
+manageable corpus. All commands stream the input files rather than loading
+them. However dedup and merge with `-dedup` keep a 64 bit hash of every
+distinct record, and sample keeps the sampled records, so their memory use
+grows with the number of distinct records and the sample size respectively.
+
+The following commands are supported:
+  - dedup: removes repeated records. With `-count`, the number of times each
+    record occurred is kept in the "meta.count" key, which the examples ignore
+    when performing detections.
+  - sample: selects `-n` records either uniformly at random (reservoir
+    sampling) or, with `-by`, `-n` records for each combination of the values
+    of the given properties (stratified sampling).
+  - merge: concatenates files, optionally removing repeated records. When
+    removing them, the "meta.count" values of repeated records are summed as
+    dedup does if any input record has one.
+  - split: splits files into files of at most `-records` records each.
+
+To run this example, perform commands such as the following:
+```
+go run evidence_corpus.go dedup -count -o deduped.yml "../20000 Evidence Records.yml"
+go run evidence_corpus.go sample -n 100 -by DeviceType,PlatformName -o sample.yml deduped.yml
+go run evidence_corpus.go merge -dedup -o merged.yml capture.yml capture.1.yml
+go run evidence_corpus.go split -records 5000 -o part.yml merged.yml
+```
+
+The output files can be read by CountEvidenceFromFiles and used as the evidence
+file of the other examples.
+*/
+
+import (
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	dd_example "github.com/51Degrees/device-detection-examples-go/v4/dd"
+	"gopkg.in/yaml.v3"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+// forEachRecord decodes each Evidence Record of the given files in turn and
+// passes it to fn.
+func forEachRecord(paths []string, fn func(record map[string]string)) {
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			dd_example.Fatalf("ERROR: Failed to open file \"%s\".\n", path)
+		}
+		dec := yaml.NewDecoder(file)
+		for {
+			// Decode Evidence file by line
+			var doc map[string]string
+			if err := dec.Decode(&doc); err == io.EOF {
+				break
+			} else if err != nil {
+				dd_example.Fatalf("ERROR: Failed during decoding file \"%s\". %v\n", path, err)
+			}
+			fn(doc)
+		}
+		if err := file.Close(); err != nil {
+			dd_example.Fatalf("ERROR: Failed to close file \"%s\".\n", path)
+		}
+	}
+}
+
+// recordHash returns a hash of the evidence in a record. Meta keys are not
+// part of the evidence so are excluded.
+func recordHash(record map[string]string) uint64 {
+	keys := make([]string, 0, len(record))
+	for k := range record {
+		if !strings.HasPrefix(k, dd_example.MetaPrefix+".") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	h := fnv.New64a()
+	for _, k := range keys {
+		// Separate with characters which cannot appear in a YAML key
+		fmt.Fprintf(h, "%s\x00%s\x00", k, record[k])
+	}
+	return h.Sum64()
+}
+
+// recordWriter writes Evidence Records to one or more output files.
+type recordWriter struct {
+	path    string
+	file    *os.File
+	enc     *dd_example.EvidenceWriter
+	records uint64
+}
+
+// sameFile returns true if a and b are paths to the same existing file.
+func sameFile(a, b string) bool {
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bi, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(ai, bi)
+}
+
+// createRecordWriter creates the output file at path. Creating the file
+// truncates it, so it must not be one of the inputs which are yet to be read.
+func createRecordWriter(path string, inputs []string) *recordWriter {
+	for _, input := range inputs {
+		if sameFile(path, input) {
+			dd_example.Fatalf("ERROR: Output file \"%s\" is also an input.\n", path)
+		}
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		dd_example.Fatalf("ERROR: Failed to create file \"%s\".\n", path)
+	}
+	return &recordWriter{path, file, dd_example.NewEvidenceWriter(file), 0}
+}
+
+func (rw *recordWriter) write(record map[string]string) {
+	if _, err := rw.enc.Write(record); err != nil {
+		dd_example.Fatalf("ERROR: Failed during encoding file \"%s\". %v\n", rw.path, err)
+	}
+	rw.records++
+}
+
+func (rw *recordWriter) close() {
+	if rw.records > 0 {
+		if err := rw.enc.Close(); err != nil {
+			dd_example.Fatalf("ERROR: Failed to write end for file \"%s\". %v\n", rw.path, err)
+		}
+	}
+	if err := rw.file.Close(); err != nil {
+		dd_example.Fatalf("ERROR: Failed to close file \"%s\".\n", rw.path)
+	}
+	fmt.Printf("Output %d records to \"%s\".\n", rw.records, rw.path)
+}
+
+// dedup writes the first occurrence of each record. When counting, a first
+// pass totals the occurrences of each record so the count can be written with
+// its first occurrence in the second pass.
+func dedup(inputs []string, output string, count bool) {
+	var counts map[uint64]uint64
+	if count {
+		counts = make(map[uint64]uint64)
+		forEachRecord(inputs, func(record map[string]string) {
+			counts[recordHash(record)] += dd_example.RecordCount(record)
+		})
+	}
+
+	out := createRecordWriter(output, inputs)
+	defer out.close()
+	seen := make(map[uint64]struct{})
+	forEachRecord(inputs, func(record map[string]string) {
+		h := recordHash(record)
+		if _, ok := seen[h]; ok {
+			return
+		}
+		seen[h] = struct{}{}
+		delete(record, dd_example.CountKey)
+		if count {
+			record[dd_example.CountKey] = strconv.FormatUint(counts[h], 10)
+		}
+		out.write(record)
+	})
+}
+
+// reservoir holds a uniform random sample of the records it has been offered.
+type reservoir struct {
+	records []map[string]string
+	offered uint64
+}
+
+// offer considers a record for the sample of size n.
+func (r *reservoir) offer(record map[string]string, n int, rnd *rand.Rand) {
+	r.offered++
+	if len(r.records) < n {
+		r.records = append(r.records, record)
+	} else if i := rnd.Int63n(int64(r.offered)); i < int64(n) {
+		r.records[i] = record
+	}
+}
+
+// stratum returns the values of the properties for a record, which together
+// identify the stratum it belongs to.
+func stratum(
+	manager *dd.ResourceManager,
+	properties []string,
+	record map[string]string) string {
+	evidence := dd_example.ExtractEvidence(dd_example.ConvertEvidenceMap(record))
+	defer evidence.Free()
+	results := dd_example.NewTrackedResults(manager, uint32(evidence.Count()), 0)
+	defer results.Free()
+	if err := results.MatchEvidence(evidence.Evidence); err != nil {
+		dd_example.Fatalf("ERROR: Failed to perform detection. %v\n", err)
+	}
+
+	values := make([]string, len(properties))
+	for i, property := range properties {
+		values[i] = "Unknown"
+		hasValues, err := results.HasValues(property)
+		if err != nil {
+			dd_example.Fatalln(err)
+		}
+		if hasValues {
+			values[i], err = results.ValuesString(property, ",")
+			if err != nil {
+				dd_example.Fatalln(err)
+			}
+		}
+	}
+	return strings.Join(values, "/")
+}
+
+// sample writes n records chosen uniformly at random or, if properties are
+// given, n records for each combination of their values.
+func sample(
+	inputs []string,
+	output string,
+	n int,
+	seed int64,
+	dataFilePath string,
+	properties []string) {
+	rnd := rand.New(rand.NewSource(seed))
+	strata := make(map[string]*reservoir)
+
+	var manager *dd_example.TrackedManager
+	if len(properties) > 0 {
+		manager = dd_example.NewTrackedManager()
+		config := dd.NewConfigHash(dd.Balanced)
+		err := dd.InitManagerFromFile(
+			manager.ResourceManager,
+			*config,
+			strings.Join(properties, ","),
+			dd_example.GetFilePathByPath(dataFilePath))
+		if err != nil {
+			dd_example.Fatalln(err)
+		}
+		defer manager.Free()
+	}
+
+	forEachRecord(inputs, func(record map[string]string) {
+		key := ""
+		if manager != nil {
+			key = stratum(manager.ResourceManager, properties, record)
+		}
+		r, ok := strata[key]
+		if !ok {
+			r = &reservoir{}
+			strata[key] = r
+		}
+		r.offer(record, n, rnd)
+	})
+
+	keys := make([]string, 0, len(strata))
+	for k := range strata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := createRecordWriter(output, inputs)
+	defer out.close()
+	for _, k := range keys {
+		r := strata[k]
+		if manager != nil {
+			fmt.Printf("%s: %d of %d records\n", k, len(r.records), r.offered)
+		}
+		for _, record := range r.records {
+			out.write(record)
+		}
+	}
+}
+
+// merge concatenates the inputs. When skipping repeated records it behaves as
+// dedup, counting occurrences if the inputs already carry counts so they are
+// summed rather than lost with the repeated records.
+func merge(inputs []string, output string, skipRepeated bool) {
+	if skipRepeated {
+		dedup(inputs, output, hasCounts(inputs))
+		return
+	}
+	out := createRecordWriter(output, inputs)
+	defer out.close()
+	forEachRecord(inputs, out.write)
+}
+
+// hasCounts returns true if any record of the inputs has a count.
+func hasCounts(inputs []string) bool {
+	found := false
+	forEachRecord(inputs, func(record map[string]string) {
+		if _, ok := record[dd_example.CountKey]; ok {
+			found = true
+		}
+	})
+	return found
+}
+
+// split writes the records of the inputs to numbered files of at most size
+// records each. For an output of "part.yml" the files are "part.1.yml",
+// "part.2.yml" and so on.
+func split(inputs []string, output string, size uint64) {
+	ext := filepath.Ext(output)
+	base := strings.TrimSuffix(output, ext)
+	var out *recordWriter
+	files := 0
+	forEachRecord(inputs, func(record map[string]string) {
+		if out == nil || out.records == size {
+			if out != nil {
+				out.close()
+			}
+			files++
+			out = createRecordWriter(
+				fmt.Sprintf("%s.%d%s", base, files, ext), inputs)
+		}
+		out.write(record)
+	})
+	if out != nil {
+		out.close()
+	}
+}
+
+// usage prints the commands and their options.
+func usage(commands map[string]*flag.FlagSet) {
+	fmt.Fprintf(os.Stderr,
+		"Usage: %s <command> [options] <evidence file>...\n\nCommands:\n",
+		filepath.Base(os.Args[0]))
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "\n%s:\n", name)
+		commands[name].PrintDefaults()
+	}
+}
+
+func main() {
+	defer dd_example.ReportLeaksAtExit()
+
+	commands := make(map[string]*flag.FlagSet)
+	newCommand := func(name string) (*flag.FlagSet, *string) {
+		fs := flag.NewFlagSet(name, flag.ExitOnError)
+		output := fs.String("o", "", "Path to the output file")
+		commands[name] = fs
+		return fs, output
+	}
+
+	dedupCmd, dedupOut := newCommand("dedup")
+	dedupCount := dedupCmd.Bool("count", false, "Keep the number of occurrences of each record in \""+dd_example.CountKey+"\"")
+
+	sampleCmd, sampleOut := newCommand("sample")
+	sampleN := sampleCmd.Int("n", 1000, "Number of records to sample, or per stratum if -by is given")
+	sampleSeed := sampleCmd.Int64("seed", time.Now().UnixNano(), "Seed of the random sampling")
+	sampleBy := sampleCmd.String("by", "", "Comma separated properties to stratify by, e.g. DeviceType,PlatformName")
+	sampleData := sampleCmd.String("data-file", "../"+dd_example.LiteDataFile, "Path to a 51Degrees Hash data file, used with -by")
+
+	mergeCmd, mergeOut := newCommand("merge")
+	mergeDedup := mergeCmd.Bool("dedup", false, "Skip repeated records")
+
+	splitCmd, splitOut := newCommand("split")
+	splitRecords := splitCmd.Uint64("records", 10000, "Maximum number of records per file")
+
+	if len(os.Args) < 2 || commands[os.Args[1]] == nil {
+		usage(commands)
+		os.Exit(2)
+	}
+	cmd := commands[os.Args[1]]
+	cmd.Parse(os.Args[2:])
+	inputs := cmd.Args()
+	if len(inputs) == 0 {
+		dd_example.Fatalln("ERROR: No evidence files given.")
+	}
+	for i, input := range inputs {
+		inputs[i] = dd_example.GetFilePathByPath(input)
+	}
+
+	// Default the output to the first input with the command as suffix
+	outputs := map[string]*string{
+		"dedup":  dedupOut,
+		"sample": sampleOut,
+		"merge":  mergeOut,
+		"split":  splitOut,
+	}
+	output := *outputs[cmd.Name()]
+	if output == "" {
+		ext := filepath.Ext(inputs[0])
+		output = fmt.Sprintf("%s.%s%s",
+			strings.TrimSuffix(inputs[0], ext), cmd.Name(), ext)
+	}
+
+	switch cmd.Name() {
+	case "dedup":
+		dedup(inputs, output, *dedupCount)
+	case "sample":
+		if *sampleN <= 0 {
+			dd_example.Fatalln("ERROR: Sample size must be greater than 0.")
+		}
+		var properties []string
+		for _, p := range strings.Split(*sampleBy, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				properties = append(properties, p)
+			}
+		}
+		sample(inputs, output, *sampleN, *sampleSeed, *sampleData, properties)
+	case "merge":
+		merge(inputs, output, *mergeDedup)
+	case "split":
+		if *splitRecords == 0 {
+			dd_example.Fatalln("ERROR: Number of records per file must be greater than 0.")
+		}
+		split(inputs, output, *splitRecords)
+	}
+}
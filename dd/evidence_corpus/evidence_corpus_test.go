@@ -0,0 +1,190 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	dd_example "github.com/51Degrees/device-detection-examples-go/v4/dd"
+)
+
+// writeRecords writes the records to a file in dir and returns its path.
+func writeRecords(
+	t *testing.T,
+	dir, name string,
+	records []map[string]string) string {
+	path := filepath.Join(dir, name)
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := dd_example.NewEvidenceWriter(file)
+	for _, record := range records {
+		if _, err := enc.Write(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readRecords returns the records of the files.
+func readRecords(paths ...string) []map[string]string {
+	var records []map[string]string
+	forEachRecord(paths, func(record map[string]string) {
+		records = append(records, record)
+	})
+	return records
+}
+
+var (
+	recordA = map[string]string{"header.user-agent": "A"}
+	recordB = map[string]string{"header.user-agent": "B"}
+	recordC = map[string]string{"header.user-agent": "C"}
+)
+
+// Test that repeated records are removed and, if required, counted.
+func TestDedup(t *testing.T) {
+	dir := t.TempDir()
+	input := writeRecords(t, dir, "input.yml", []map[string]string{
+		recordA,
+		recordB,
+		{"header.user-agent": "A", "meta.count": "2"},
+		recordC,
+		recordB,
+	})
+
+	testData := []struct {
+		count    bool
+		expected []map[string]string
+	}{
+		{false, []map[string]string{recordA, recordB, recordC}},
+		{true, []map[string]string{
+			{"header.user-agent": "A", "meta.count": "3"},
+			{"header.user-agent": "B", "meta.count": "2"},
+			{"header.user-agent": "C", "meta.count": "1"},
+		}},
+	}
+
+	for _, data := range testData {
+		output := filepath.Join(dir, "output.yml")
+		dedup([]string{input}, output, data.count)
+		if records := readRecords(output); !reflect.DeepEqual(records, data.expected) {
+			t.Errorf("Expected '%v', but got '%v'", data.expected, records)
+		}
+	}
+}
+
+// Test that files are concatenated and that removing repeated records sums
+// their counts.
+func TestMerge(t *testing.T) {
+	dir := t.TempDir()
+	first := writeRecords(t, dir, "first.yml", []map[string]string{
+		{"header.user-agent": "A", "meta.count": "2"},
+		recordB,
+	})
+	second := writeRecords(t, dir, "second.yml", []map[string]string{
+		{"header.user-agent": "A", "meta.count": "5"},
+		recordC,
+	})
+	inputs := []string{first, second}
+	output := filepath.Join(dir, "output.yml")
+
+	merge(inputs, output, false)
+	expected := readRecords(first, second)
+	if records := readRecords(output); !reflect.DeepEqual(records, expected) {
+		t.Errorf("Expected '%v', but got '%v'", expected, records)
+	}
+
+	merge(inputs, output, true)
+	expected = []map[string]string{
+		{"header.user-agent": "A", "meta.count": "7"},
+		{"header.user-agent": "B", "meta.count": "1"},
+		{"header.user-agent": "C", "meta.count": "1"},
+	}
+	if records := readRecords(output); !reflect.DeepEqual(records, expected) {
+		t.Errorf("Expected '%v', but got '%v'", expected, records)
+	}
+
+	// Without counts in the inputs none are added
+	third := writeRecords(t, dir, "third.yml", []map[string]string{
+		recordA, recordB, recordA,
+	})
+	merge([]string{third}, output, true)
+	expected = []map[string]string{recordA, recordB}
+	if records := readRecords(output); !reflect.DeepEqual(records, expected) {
+		t.Errorf("Expected '%v', but got '%v'", expected, records)
+	}
+}
+
+// Test that the sample has the requested size, only contains input records
+// and is the same for the same seed.
+func TestSample(t *testing.T) {
+	dir := t.TempDir()
+	var records []map[string]string
+	for i := 0; i < 100; i++ {
+		records = append(records, map[string]string{
+			"header.user-agent": string(rune('A' + i%26)),
+			"query.index":       string(rune('0' + i%10)),
+		})
+	}
+	input := writeRecords(t, dir, "input.yml", records)
+	inputs := []string{input}
+
+	first := filepath.Join(dir, "first.yml")
+	second := filepath.Join(dir, "second.yml")
+	sample(inputs, first, 10, 42, "", nil)
+	sample(inputs, second, 10, 42, "", nil)
+
+	sampled := readRecords(first)
+	if len(sampled) != 10 {
+		t.Fatalf("Expected '10' records, but got '%d'", len(sampled))
+	}
+	hashes := make(map[uint64]bool)
+	for _, record := range records {
+		hashes[recordHash(record)] = true
+	}
+	for _, record := range sampled {
+		if !hashes[recordHash(record)] {
+			t.Errorf("Record '%v' is not in the input", record)
+		}
+	}
+	if again := readRecords(second); !reflect.DeepEqual(sampled, again) {
+		t.Errorf("Expected the same sample for the same seed, but got "+
+			"'%v' and '%v'", sampled, again)
+	}
+
+	// A sample larger than the input contains every record
+	sample(inputs, first, 1000, 42, "", nil)
+	if n := len(readRecords(first)); n != len(records) {
+		t.Errorf("Expected '%d' records, but got '%d'", len(records), n)
+	}
+}
@@ -32,10 +32,13 @@ import (
 	"flag"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
 
 	"github.com/51Degrees/device-detection-go/v4/dd"
 	"gopkg.in/yaml.v3"
@@ -47,6 +50,39 @@ const EnterpriseDataFile = "Enterprise-HashV41.hash"
 const UaFileCSV = "20000 User Agents.csv"
 const EvidenceFileYaml = "20000 Evidence Records.yml"
 
+// Prefix of Evidence Record keys which carry information about the record
+// itself, such as how often it occurred, rather than evidence. They are not
+// passed to the engine.
+const MetaPrefix = "meta"
+
+// Key of the number of times an Evidence Record occurred in the original
+// corpus, written when records are deduplicated.
+const CountKey = MetaPrefix + ".count"
+
+// RecordCount returns the number of times an Evidence Record occurred, which
+// is 1 unless the record has been deduplicated with counts.
+func RecordCount(record map[string]string) uint64 {
+	if v, ok := record[CountKey]; ok {
+		if n, err := strconv.ParseUint(v, 10, 64); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 1
+}
+
+// MatchMethodName returns the name of a match method as used in reports.
+func MatchMethodName(method dd.MatchMethod) string {
+	switch method {
+	case dd.Performance:
+		return "PERFORMANCE"
+	case dd.Combined:
+		return "COMBINED"
+	case dd.Predictive:
+		return "PREDICTIVE"
+	}
+	return "NONE"
+}
+
 // Evidence where all fields are in string format
 type stringEvidence struct {
 	Prefix string
@@ -58,9 +94,11 @@ type stringEvidence struct {
 func ConvertEvidenceMap(values map[string]string) []stringEvidence {
 	evidence := make([]stringEvidence, 0)
 	for k, v := range values {
-		strSplit := strings.SplitN(k, ".", 2)
-		prefixStr := strSplit[0]
-		keyStr := strSplit[1]
+		prefixStr, keyStr, found := strings.Cut(k, ".")
+		if !found || prefixStr == MetaPrefix {
+			// Keys without a prefix are not evidence
+			continue
+		}
 		evidence = append(
 			evidence, stringEvidence{prefixStr, keyStr, v})
 	}
@@ -69,16 +107,29 @@ func ConvertEvidenceMap(values map[string]string) []stringEvidence {
 
 // ExtractEvidence looks into a list of required evidence keys and extract
 // them.
-func ExtractEvidence(strEvidence []stringEvidence) *dd.Evidence {
-	evidence := dd.NewEvidenceHash(uint32(len(strEvidence)))
+func ExtractEvidence(strEvidence []stringEvidence) *TrackedEvidence {
+	evidence := NewTrackedEvidence(uint32(len(strEvidence)))
+	addEvidence(evidence.Evidence, strEvidence)
+	return evidence
+}
+
+// addEvidence adds the evidence strings to the evidence with the prefix
+// matching each.
+func addEvidence(evidence *dd.Evidence, strEvidence []stringEvidence) {
 	for _, e := range strEvidence {
-		prefix := dd.HttpHeaderString
-		if e.Prefix == "query" {
+		var prefix dd.EvidencePrefix
+		switch e.Prefix {
+		case "query":
 			prefix = dd.HttpEvidenceQuery
+		case "cookie":
+			prefix = dd.HttpEvidenceCookie
+		case "server":
+			prefix = dd.HttpEvidenceServer
+		default:
+			prefix = dd.HttpHeaderString
 		}
 		evidence.Add(prefix, e.Key, e.Value)
 	}
-	return evidence
 }
 
 // Type take a performance profile, run the code and get the return output
@@ -92,7 +143,7 @@ func GetFilePathByName(names []string) string {
 		names,
 	)
 	if err != nil {
-		log.Fatalf("Could not find any file that matches any of \"%s\".\n",
+		Fatalf("Could not find any file that matches any of \"%s\".\n",
 			strings.Join(names, ", "))
 	}
 	return filePath
@@ -106,7 +157,7 @@ func GetFilePathByPath(path string) string {
 		[]string{file},
 	)
 	if err != nil {
-		log.Fatalf("Could not find any file that matches \"%s\" at path \"%s\".\n",
+		Fatalf("Could not find any file that matches \"%s\" at path \"%s\".\n",
 			file,
 			dir)
 	}
@@ -132,11 +183,11 @@ func CountUAFromFiles(
 	// Count the number of User Agents
 	f, err := os.OpenFile(uaFilePath, os.O_RDONLY, 0444)
 	if err != nil {
-		log.Fatalf("ERROR: Failed to open file \"%s\".\n", uaFilePath)
+		Fatalf("ERROR: Failed to open file \"%s\".\n", uaFilePath)
 	}
 	defer func() {
 		if err := f.Close(); err != nil {
-			log.Fatalf("ERROR: Failed to close file \"%s\".\n", uaFilePath)
+			Fatalf("ERROR: Failed to close file \"%s\".\n", uaFilePath)
 		}
 	}()
 
@@ -144,7 +195,7 @@ func CountUAFromFiles(
 	s := bufio.NewScanner(f)
 	defer func() {
 		if err := s.Err(); err != nil {
-			log.Fatalf("ERROR: Error during scanning file\"%s\".\n", uaFilePath)
+			Fatalf("ERROR: Error during scanning file\"%s\".\n", uaFilePath)
 		}
 	}()
 
@@ -163,11 +214,11 @@ func CountEvidenceFromFiles(
 	// Count the number of Evidence Records
 	f, err := os.OpenFile(evidenceFilePath, os.O_RDONLY, 0444)
 	if err != nil {
-		log.Fatalf("ERROR: Failed to open file \"%s\".\n", evidenceFilePath)
+		Fatalf("ERROR: Failed to open file \"%s\".\n", evidenceFilePath)
 	}
 	defer func() {
 		if err := f.Close(); err != nil {
-			log.Fatalf("ERROR: Failed to close file \"%s\".\n", evidenceFilePath)
+			Fatalf("ERROR: Failed to close file \"%s\".\n", evidenceFilePath)
 		}
 	}()
 
@@ -180,41 +231,205 @@ func CountEvidenceFromFiles(
 			break
 		} else if err != nil {
 			// Make sure there is no decoder error
-			log.Fatalf("ERROR: Failed during decoding file \"%s\". %v\n", evidenceFilePath, err)
+			Fatalf("ERROR: Failed during decoding file \"%s\". %v\n", evidenceFilePath, err)
 		}
 		count++
 	}
 	return count
 }
 
-// This is a wrapper function which execute a function that contains
-// example code with an input performance profile or all performance
-// profiles if performed under CI.
-func PerformExample(perf dd.PerformanceProfile, eFunc ExampleFunc) {
-	perfs := []dd.PerformanceProfile{perf}
-	// If running under ci, use all performance profiles
-	if isFlagOn("ci") {
-		perfs = []dd.PerformanceProfile{
-			dd.Default,
-			dd.LowMemory,
-			dd.Balanced,
-			dd.BalancedTemp,
-			dd.HighPerformance,
-			dd.InMemory,
+// Performance profiles used by PerformExample when running under CI
+var ciProfiles = []dd.PerformanceProfile{
+	dd.Default,
+	dd.LowMemory,
+	dd.Balanced,
+	dd.BalancedTemp,
+	dd.HighPerformance,
+	dd.InMemory,
+}
+
+// ProfileName returns the name of a performance profile.
+func ProfileName(p dd.PerformanceProfile) string {
+	switch p {
+	case dd.Default:
+		return "Default"
+	case dd.LowMemory:
+		return "LowMemory"
+	case dd.BalancedTemp:
+		return "BalancedTemp"
+	case dd.Balanced:
+		return "Balanced"
+	case dd.HighPerformance:
+		return "HighPerformance"
+	case dd.InMemory:
+		return "InMemory"
+	}
+	return fmt.Sprintf("Profile %d", p)
+}
+
+// Total time taken by InitManagerFromFile, used by PerformExample to separate
+// the initialisation time of an example from its run time.
+var initTime struct {
+	sync.Mutex
+	total time.Duration
+}
+
+// InitManagerFromFile is the same as dd.InitManagerFromFile, and also records
+// the time taken so that PerformExample can report it for each profile.
+func InitManagerFromFile(
+	manager *dd.ResourceManager,
+	config dd.ConfigHash,
+	properties string,
+	filePath string) error {
+	start := time.Now()
+	err := dd.InitManagerFromFile(manager, config, properties, filePath)
+	initTime.Lock()
+	initTime.total += time.Since(start)
+	initTime.Unlock()
+	return err
+}
+
+// totalInitTime returns the time taken by InitManagerFromFile so far.
+func totalInitTime() time.Duration {
+	initTime.Lock()
+	defer initTime.Unlock()
+	return initTime.total
+}
+
+// ProfileRun is the output of an example run with a performance profile, and
+// the time taken to initialise resource managers and to run the rest of it.
+type ProfileRun struct {
+	Profile dd.PerformanceProfile
+	Output  string
+	Init    time.Duration
+	Run     time.Duration
+}
+
+// runProfile runs an example function with a performance profile.
+func runProfile(p dd.PerformanceProfile, run func() string) ProfileRun {
+	initBefore := totalInitTime()
+	start := time.Now()
+	output := run()
+	elapsed := time.Since(start)
+	init := totalInitTime() - initBefore
+	return ProfileRun{p, output, init, elapsed - init}
+}
+
+// CompareProfileRuns returns a diff of the output of each run which differs
+// from the output of the first, or an empty string if they are all the same.
+func CompareProfileRuns(runs []ProfileRun) string {
+	var b strings.Builder
+	for _, r := range runs[1:] {
+		if r.Output != runs[0].Output {
+			fmt.Fprintf(&b, "--- %s\n+++ %s\n%s",
+				ProfileName(runs[0].Profile),
+				ProfileName(r.Profile),
+				DiffLines(runs[0].Output, r.Output))
+		}
+	}
+	return b.String()
+}
+
+// DiffLines returns the lines which differ between two texts, prefixed with
+// '-' if only in a and '+' if only in b. Lines in both are prefixed with ' '.
+func DiffLines(a, b string) string {
+	x, y := strings.Split(a, "\n"), strings.Split(b, "\n")
+	// Length of the longest common subsequence of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
 		}
 	}
+	var d strings.Builder
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			fmt.Fprintf(&d, " %s\n", x[i])
+			i++
+			j++
+		case j < len(y) && (i == len(x) || lcs[i][j+1] > lcs[i+1][j]):
+			fmt.Fprintf(&d, "+%s\n", y[j])
+			j++
+		default:
+			fmt.Fprintf(&d, "-%s\n", x[i])
+			i++
+		}
+	}
+	return d.String()
+}
 
-	// Execute the example function with all performance profiles
+// performProfiles runs an example function with each performance profile.
+// The output of the first is printed to support example Output verification.
+// If there is more than one profile, the time taken by each is reported and
+// the program fails if their outputs differ. Tracked handles which have not
+// been freed are reported at the end.
+func performProfiles(perfs []dd.PerformanceProfile, run func(p dd.PerformanceProfile) string) {
+	defer ReportLeaksAtExit()
+	runs := make([]ProfileRun, len(perfs))
 	for i, p := range perfs {
-		output := eFunc(p)
-		// This is to support example Output verification
-		// so only print once.
+		runs[i] = runProfile(p, func() string { return run(p) })
 		if i == 0 {
-			fmt.Print(output)
+			fmt.Print(runs[i].Output)
+		}
+	}
+	if len(runs) < 2 {
+		return
+	}
+
+	// Report to stderr so the output is unchanged
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Profile\tInit\tRun\tOutput\t")
+	for _, r := range runs {
+		same := "same"
+		if r.Output != runs[0].Output {
+			same = "DIFFERENT"
 		}
+		fmt.Fprintf(w, "%s\t%v\t%v\t%s\t\n",
+			ProfileName(r.Profile),
+			r.Init.Round(time.Microsecond),
+			r.Run.Round(time.Microsecond),
+			same)
+	}
+	w.Flush()
+
+	if diff := CompareProfileRuns(runs); diff != "" {
+		Fatalf("ERROR: Output differs between performance profiles.\n%s", diff)
 	}
 }
 
+// This is a wrapper function which execute a function that contains
+// example code with an input performance profile or all performance
+// profiles if performed under CI. Under CI the outputs of all profiles must
+// be the same.
+func PerformExample(perf dd.PerformanceProfile, eFunc ExampleFunc) {
+	// Execute the example function with all performance profiles
+	performProfiles(ExampleProfiles(perf), func(p dd.PerformanceProfile) string {
+		return eFunc(p)
+	})
+}
+
+// ExampleProfiles returns the performance profile given, or all performance
+// profiles if performed under CI. It is used by the tests of the examples to
+// run them as PerformExample does.
+func ExampleProfiles(perf dd.PerformanceProfile) []dd.PerformanceProfile {
+	// If running under ci, use all performance profiles
+	if isFlagOn("ci") {
+		return ciProfiles
+	}
+	return []dd.PerformanceProfile{perf}
+}
+
 // Same as PerformExample with additional support for command line options
 func PerformExampleOptions(perf dd.PerformanceProfile, eFunc ExampleOptFunc) {
 	// Get command line options
@@ -224,28 +439,10 @@ func PerformExampleOptions(perf dd.PerformanceProfile, eFunc ExampleOptFunc) {
 		return
 	}
 
-	perfs := []dd.PerformanceProfile{perf}
-	// If running under ci, use all performance profiles
-	if isFlagOn("ci") {
-		perfs = []dd.PerformanceProfile{
-			dd.Default,
-			dd.LowMemory,
-			dd.Balanced,
-			dd.BalancedTemp,
-			dd.HighPerformance,
-			dd.InMemory,
-		}
-	}
-
 	// Execute the example function with all performance profiles
-	for i, p := range perfs {
-		output := eFunc(p, options)
-		// This is to support example Output verification
-		// so only print once.
-		if i == 0 {
-			fmt.Print(output)
-		}
-	}
+	performProfiles(ExampleProfiles(perf), func(p dd.PerformanceProfile) string {
+		return eFunc(p, options)
+	})
 }
 
 type Options struct {
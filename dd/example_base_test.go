@@ -0,0 +1,118 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package dd_example
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+// Test that only the lines which differ are marked.
+func TestDiffLines(t *testing.T) {
+	a := "Mobile\n\tIsMobile: True\nDesktop\n\tIsMobile: False\n"
+	b := "Mobile\n\tIsMobile: False\nDesktop\n\tIsMobile: False\n"
+	expected := " Mobile\n" +
+		"-\tIsMobile: True\n" +
+		"+\tIsMobile: False\n" +
+		" Desktop\n" +
+		" \tIsMobile: False\n" +
+		" \n"
+	if actual := DiffLines(a, b); actual != expected {
+		t.Errorf("Expected diff:\n%s\nGot:\n%s", expected, actual)
+	}
+}
+
+// Test that runs are only reported when their output differs from the first.
+func TestCompareProfileRuns(t *testing.T) {
+	runs := []ProfileRun{
+		{Profile: dd.Default, Output: "a\n"},
+		{Profile: dd.LowMemory, Output: "a\n"},
+		{Profile: dd.InMemory, Output: "b\n"},
+	}
+	expected := "--- Default\n+++ InMemory\n-a\n+b\n \n"
+	if actual := CompareProfileRuns(runs); actual != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, actual)
+	}
+	if actual := CompareProfileRuns(runs[:2]); actual != "" {
+		t.Errorf("Expected no differences, but got:\n%s", actual)
+	}
+}
+
+// Fuzz the conversion of Evidence Records entries, and their extraction into
+// evidence, with a key and value added to a record. Entries must keep their
+// prefix, key and value, and only the meta entries and keys without a prefix
+// are dropped.
+func FuzzConvertEvidenceMap(f *testing.F) {
+	fixtures, err := Fixtures()
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, fixture := range fixtures {
+		for k, v := range fixture.Record {
+			f.Add(k, v)
+		}
+	}
+	f.Add("user-agent", iPhoneUA)
+	f.Add(".", "")
+	f.Add("header.", "")
+	f.Add("meta.", "")
+	f.Add("header.a.b", "value")
+
+	f.Fuzz(func(t *testing.T, key, value string) {
+		record := map[string]string{
+			"header.user-agent": iPhoneUA,
+			"meta.name":         "fuzz",
+			key:                 value,
+		}
+		expected := 0
+		for k := range record {
+			if prefix, _, found := strings.Cut(k, "."); found && prefix != MetaPrefix {
+				expected++
+			}
+		}
+
+		strEvidence := ConvertEvidenceMap(record)
+		if len(strEvidence) != expected {
+			t.Fatalf("Expected %d evidence for %v, but got %d",
+				expected, record, len(strEvidence))
+		}
+		for _, e := range strEvidence {
+			if e.Prefix == MetaPrefix {
+				t.Errorf("Unexpected meta evidence '%s'", e.Key)
+			}
+			if v, ok := record[e.Prefix+"."+e.Key]; !ok || v != e.Value {
+				t.Errorf("Evidence '%s.%s: %s' is not in %v",
+					e.Prefix, e.Key, e.Value, record)
+			}
+		}
+
+		evidence := ExtractEvidence(strEvidence)
+		defer evidence.Free()
+		if evidence.Count() != len(strEvidence) {
+			t.Errorf("Expected %d extracted evidence, but got %d",
+				len(strEvidence), evidence.Count())
+		}
+	})
+}
@@ -0,0 +1,202 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package dd_example
+
+/*
+This file contains a YAML format pairing evidence with the values expected to
+be detected for it, and the verification of a data file against it. An
+expected value is either a string which must match exactly, or a mapping with
+one of the following keys:
+  - regex: a regular expression which must match the whole value,
+  - any-of: a list of values, one of which must match exactly.
+
+For example:
+```
+- name: iPhone
+  evidence:
+    header.user-agent: Mozilla/5.0 (iPhone; CPU iPhone OS 7_1 like Mac OS X) ...
+  expect:
+    IsMobile: "True"
+    PlatformName: { regex: "iOS|iPadOS" }
+    BrowserName: { any-of: [ Mobile Safari, Safari ] }
+```
+*/
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+	"gopkg.in/yaml.v3"
+)
+
+// ExpectedValue is the value expected for a property.
+type ExpectedValue struct {
+	Exact string
+	Regex *regexp.Regexp
+	AnyOf []string
+}
+
+// UnmarshalYAML reads an expected value from a string or a mapping with a
+// regex or any-of key.
+func (e *ExpectedValue) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&e.Exact)
+	}
+	var m struct {
+		Regex *string  `yaml:"regex"`
+		AnyOf []string `yaml:"any-of"`
+	}
+	if err := node.Decode(&m); err != nil {
+		return err
+	}
+	switch {
+	case m.Regex != nil && m.AnyOf == nil:
+		regex, err := regexp.Compile("^(?:" + *m.Regex + ")$")
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		e.Regex = regex
+	case m.AnyOf != nil && m.Regex == nil:
+		if len(m.AnyOf) == 0 {
+			return fmt.Errorf("line %d: any-of has no values", node.Line)
+		}
+		e.AnyOf = m.AnyOf
+	default:
+		return fmt.Errorf("line %d: expected a value, regex or any-of", node.Line)
+	}
+	return nil
+}
+
+// Matches returns true if the detected value is the one expected.
+func (e ExpectedValue) Matches(value string) bool {
+	switch {
+	case e.Regex != nil:
+		return e.Regex.MatchString(value)
+	case e.AnyOf != nil:
+		for _, v := range e.AnyOf {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	}
+	return e.Exact == value
+}
+
+// matchesValue returns true if the property has a value and it is the one
+// expected. A missing value never matches, even if its reason would.
+func (e ExpectedValue) matchesValue(v PropertyValue) bool {
+	return v.HasValue() && e.Matches(v.Value())
+}
+
+func (e ExpectedValue) String() string {
+	switch {
+	case e.Regex != nil:
+		// Without the anchors added when compiling
+		s := e.Regex.String()
+		return "regex " + s[len("^(?:"):len(s)-len(")$")]
+	case e.AnyOf != nil:
+		return "any of " + strings.Join(e.AnyOf, ", ")
+	}
+	return e.Exact
+}
+
+// ExpectedResult pairs evidence, keyed as in an Evidence Records file, with the
+// values expected for it keyed by property name.
+type ExpectedResult struct {
+	Name     string                   `yaml:"name"`
+	Evidence map[string]string        `yaml:"evidence"`
+	Expect   map[string]ExpectedValue `yaml:"expect"`
+}
+
+// ReadExpectedResults reads a list of expected results.
+func ReadExpectedResults(r io.Reader) ([]ExpectedResult, error) {
+	var expected []ExpectedResult
+	if err := yaml.NewDecoder(r).Decode(&expected); err != nil && err != io.EOF {
+		return nil, err
+	}
+	for i, e := range expected {
+		if len(e.Evidence) == 0 || len(e.Expect) == 0 {
+			return nil, fmt.Errorf(
+				"expected result %d '%s' needs evidence and expected values",
+				i+1, e.Name)
+		}
+	}
+	return expected, nil
+}
+
+// Mismatch is a property whose detected value is not the one expected.
+type Mismatch struct {
+	Name     string
+	Property string
+	Expected ExpectedValue
+	Actual   PropertyValue
+}
+
+func (m Mismatch) String() string {
+	return fmt.Sprintf("%s: expected %s to be '%s', but got '%s'",
+		m.Name, m.Property, m.Expected, m.Actual)
+}
+
+// VerifyExpectedResults performs a detection for each expected result and
+// returns the properties whose values are not the ones expected, including
+// properties which have no value, e.g. as they are not in the data file.
+func VerifyExpectedResults(
+	manager *dd.ResourceManager,
+	expected []ExpectedResult) ([]Mismatch, error) {
+	mismatches := make([]Mismatch, 0)
+	for _, e := range expected {
+		evidence := ExtractEvidence(ConvertEvidenceMap(e.Evidence))
+		results := NewTrackedResults(manager, uint32(evidence.Count()), 0)
+		err := results.MatchEvidence(evidence.Evidence)
+		if err == nil {
+			// Properties in order so the mismatches are the same every time
+			properties := make([]string, 0, len(e.Expect))
+			for p := range e.Expect {
+				properties = append(properties, p)
+			}
+			sort.Strings(properties)
+			for _, p := range properties {
+				actual := GetPropertyValue(results.ResultsHash, p)
+				if !e.Expect[p].matchesValue(actual) {
+					mismatches = append(mismatches, Mismatch{
+						Name:     e.Name,
+						Property: p,
+						Expected: e.Expect[p],
+						Actual:   actual,
+					})
+				}
+			}
+		}
+		results.Free()
+		evidence.Free()
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to perform detection: %w", e.Name, err)
+		}
+	}
+	return mismatches, nil
+}
@@ -0,0 +1,146 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package dd_example
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/51Degrees/device-detection-examples-go/v4/dd/internal/testutil"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+// Test that exact, regex and any-of values are read and matched.
+func TestReadExpectedResults(t *testing.T) {
+	expected, err := ReadExpectedResults(strings.NewReader(`
+- name: iPhone
+  evidence:
+    header.user-agent: ` + iPhoneUA + `
+  expect:
+    IsMobile: "True"
+    PlatformVersion: { regex: "7(\\.1)?" }
+    BrowserName: { any-of: [ Mobile Safari, Safari ] }
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expected) != 1 || expected[0].Name != "iPhone" ||
+		expected[0].Evidence["header.user-agent"] != iPhoneUA {
+		t.Fatalf("Unexpected expected results %+v", expected)
+	}
+
+	testData := []struct {
+		property string
+		value    string
+		matches  bool
+	}{
+		{"IsMobile", "True", true},
+		{"IsMobile", "true", false},
+		{"PlatformVersion", "7.1", true},
+		{"PlatformVersion", "7", true},
+		{"PlatformVersion", "17.1", false},
+		{"BrowserName", "Safari", true},
+		{"BrowserName", "Chrome", false},
+	}
+	for _, data := range testData {
+		e := expected[0].Expect[data.property]
+		if e.Matches(data.value) != data.matches {
+			t.Errorf("Expected %s '%s' matching '%s' to be %v",
+				data.property, e, data.value, data.matches)
+		}
+	}
+	if s := expected[0].Expect["PlatformVersion"].String(); s != `regex 7(\.1)?` {
+		t.Errorf("Unexpected description '%s'", s)
+	}
+}
+
+// Test that invalid expected results are reported.
+func TestReadExpectedResultsInvalid(t *testing.T) {
+	for _, data := range []string{
+		"- name: no evidence\n  expect:\n    IsMobile: \"True\"\n",
+		"- name: bad regex\n  evidence:\n    header.user-agent: ua\n  expect:\n    IsMobile: { regex: \"(\" }\n",
+		"- name: both\n  evidence:\n    header.user-agent: ua\n  expect:\n    IsMobile: { regex: a, any-of: [ b ] }\n",
+		"- name: empty\n  evidence:\n    header.user-agent: ua\n  expect:\n    IsMobile: { any-of: [] }\n",
+	} {
+		if _, err := ReadExpectedResults(strings.NewReader(data)); err == nil {
+			t.Errorf("Expected an error for:\n%s", data)
+		}
+	}
+}
+
+// Test that a property without a value never matches, even where the reason
+// it has no value would.
+func TestExpectedValueMissing(t *testing.T) {
+	for _, e := range []ExpectedValue{
+		{Exact: "Not loaded"},
+		{Regex: regexp.MustCompile(".*")},
+		{AnyOf: []string{"No value", "Null"}},
+	} {
+		for _, reason := range []ValueReason{ValueNotLoaded, ValueNoValue, ValueNull} {
+			if v := (PropertyValue{Reason: reason}); e.matchesValue(v) {
+				t.Errorf("Expected '%s' not to match '%s'", e, v)
+			}
+		}
+	}
+	// A value found matches, even if it is the name of a reason
+	e := ExpectedValue{Exact: "Null"}
+	if v := (PropertyValue{Values: []string{"Null"}}); !e.matchesValue(v) {
+		t.Errorf("Expected '%s' to match '%s'", e, v)
+	}
+}
+
+// Test that mismatches are reported for values which are not expected, and
+// for properties which are not in the data file.
+func TestVerifyExpectedResults(t *testing.T) {
+	filePath := testutil.DataFilePathOrSkip(t)
+	manager := dd.NewResourceManager()
+	config := dd.NewConfigHash(dd.Balanced)
+	if err := dd.InitManagerFromFile(manager, *config, "", filePath); err != nil {
+		t.Fatalf("Failed to initialize resource manager. %v", err)
+	}
+	defer manager.Free()
+
+	expected, err := ReadExpectedResults(strings.NewReader(`
+- name: iPhone
+  evidence:
+    header.user-agent: ` + iPhoneUA + `
+  expect:
+    IsMobile: "True"
+    PlatformName: Android
+    NoSuchProperty: value
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mismatches, err := VerifyExpectedResults(manager, expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) != 2 ||
+		mismatches[0].Property != "NoSuchProperty" ||
+		mismatches[1].Property != "PlatformName" {
+		t.Errorf("Unexpected mismatches %v", mismatches)
+	}
+}
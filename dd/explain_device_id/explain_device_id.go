@@ -0,0 +1,195 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package main
+
+/*
+This example illustrates how a device ID can be explained by the profiles it
+is made of, e.g. to find out why two visitors got different results.
+
+Given a device ID, the profile ID of each component is printed with the values
+of the component's properties:
+```
+go run explain_device_id.go 12280-101474-102327-18092
+```
+
+Given two device IDs, the properties with different values are printed for
+each component, and components with the same profile are skipped:
+```
+go run explain_device_id.go 12280-101474-102327-18092 12280-101474-102327-0
+```
+
+A device ID can also be detected from a User-Agent with `-ua`, in place of the
+first or only device ID.
+*/
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	dd_example "github.com/51Degrees/device-detection-examples-go/v4/dd"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+type options struct {
+	DataFilePath string
+	UserAgent    string
+	showHelp     bool
+}
+
+func parseOptions() options {
+	o := options{}
+
+	flag.StringVar(&o.DataFilePath, "data-file", "../"+dd_example.LiteDataFile, "Path to a 51Degrees Hash data file")
+	flag.StringVar(&o.DataFilePath, "d", o.DataFilePath, "Alias for -data-file")
+
+	flag.StringVar(&o.UserAgent, "ua", "", "User-Agent to detect a device ID from")
+
+	flag.BoolVar(&o.showHelp, "help", false, "Print help")
+	flag.BoolVar(&o.showHelp, "h", o.showHelp, "Alias for -help")
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"Usage: %s [options] [device id] [device id]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	return o
+}
+
+// detectDeviceId returns the device ID detected from a User-Agent.
+func detectDeviceId(manager *dd.ResourceManager, ua string) string {
+	results := dd_example.NewTrackedResults(manager, 1, 0)
+	defer results.Free()
+	if err := results.MatchUserAgent(ua); err != nil {
+		dd_example.Fatalf("ERROR: Failed to perform detection on User-Agent \"%s\".\n", ua)
+	}
+	deviceId, err := results.DeviceId()
+	if err != nil {
+		dd_example.Fatalf("ERROR: Failed to get device id. %v\n", err)
+	}
+	return deviceId
+}
+
+// explain prints the profiles of a device ID and their property values.
+func explain(w io.Writer, manager *dd.ResourceManager, id dd_example.DeviceId) {
+	profiles, err := dd_example.DecomposeDeviceId(manager, id)
+	if err != nil {
+		dd_example.Fatalf("ERROR: Failed to decompose device id \"%s\". %v\n", id, err)
+	}
+	fmt.Fprintf(w, "Device ID: %s\n", id)
+	for _, p := range profiles {
+		fmt.Fprintf(w, "\n%s: %d\n", p.Component, p.ProfileId)
+		properties := make([]string, 0, len(p.Values))
+		for property := range p.Values {
+			properties = append(properties, property)
+		}
+		sort.Strings(properties)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, property := range properties {
+			fmt.Fprintf(tw, "\t%s\t%s\n", property, p.Values[property])
+		}
+		tw.Flush()
+	}
+}
+
+// diff prints the properties with different values for two device IDs.
+func diff(w io.Writer, manager *dd.ResourceManager, a, b dd_example.DeviceId) {
+	diffs, err := dd_example.DiffDeviceIds(manager, a, b)
+	if err != nil {
+		dd_example.Fatalf("ERROR: Failed to compare device ids. %v\n", err)
+	}
+	fmt.Fprintf(w, "A: %s\nB: %s\n", a, b)
+	same := true
+	for _, d := range diffs {
+		if d.A == d.B {
+			continue
+		}
+		same = false
+		fmt.Fprintf(w, "\n%s: %d -> %d\n", d.Component, d.A, d.B)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "\tProperty\tA\tB")
+		for _, p := range d.Properties {
+			fmt.Fprintf(tw, "\t%s\t%s\t%s\n", p.Property, orNone(p.A), orNone(p.B))
+		}
+		tw.Flush()
+	}
+	if same {
+		fmt.Fprintln(w, "\nThe device IDs have the same profiles.")
+	}
+}
+
+// orNone returns the value, or a placeholder if there is no value.
+func orNone(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+func main() {
+	defer dd_example.ReportLeaksAtExit()
+
+	o := parseOptions()
+	if o.showHelp {
+		flag.Usage()
+		return
+	}
+
+	// Initialise manager
+	manager := dd_example.NewTrackedManager()
+	config := dd.NewConfigHash(dd.Balanced)
+	filePath := dd_example.GetFilePathByPath(o.DataFilePath)
+	err := dd.InitManagerFromFile(manager.ResourceManager, *config, "", filePath)
+	if err != nil {
+		dd_example.Fatalln(err)
+	}
+
+	// Make sure manager object will be freed after the function execution
+	defer manager.Free()
+
+	args := flag.Args()
+	if o.UserAgent != "" {
+		args = append([]string{detectDeviceId(manager.ResourceManager, o.UserAgent)}, args...)
+	}
+	if len(args) == 0 || len(args) > 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ids := make([]dd_example.DeviceId, len(args))
+	for i, arg := range args {
+		if ids[i], err = dd_example.ParseDeviceId(arg); err != nil {
+			dd_example.Fatalf("ERROR: %v\n", err)
+		}
+	}
+	if len(ids) == 1 {
+		explain(os.Stdout, manager.ResourceManager, ids[0])
+	} else {
+		diff(os.Stdout, manager.ResourceManager, ids[0], ids[1])
+	}
+}
@@ -0,0 +1,100 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	dd_example "github.com/51Degrees/device-detection-examples-go/v4/dd"
+	"github.com/51Degrees/device-detection-examples-go/v4/dd/internal/testutil"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+// User-Agent of an iPhone, which has a profile for each of the hardware,
+// platform and browser components
+const iPhoneUA = "Mozilla/5.0 (iPhone; CPU iPhone OS 7_1 like Mac OS X) " +
+	"AppleWebKit/537.51.2 (KHTML, like Gecko) Version/7.0 Mobile/11D167 " +
+	"Safari/9537.53"
+
+// Test that missing values are shown with a placeholder.
+func TestOrNone(t *testing.T) {
+	if v := orNone(""); v != "-" {
+		t.Errorf("Expected '-', but got '%s'", v)
+	}
+	if v := orNone("Apple"); v != "Apple" {
+		t.Errorf("Expected 'Apple', but got '%s'", v)
+	}
+}
+
+// Test that a device ID is explained by the named profiles of its components,
+// and that two device IDs are compared only where their profiles differ.
+func TestExplainAndDiff(t *testing.T) {
+	manager := dd.NewResourceManager()
+	config := dd.NewConfigHash(dd.Balanced)
+	err := dd.InitManagerFromFile(
+		manager, *config, "", testutil.DataFilePathOrSkip(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Free()
+
+	id, err := dd_example.ParseDeviceId(detectDeviceId(manager, iPhoneUA))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	explain(&out, manager, id)
+	for _, expected := range []string{
+		"Device ID: " + id.String(),
+		"Hardware: ",
+		"Platform: ",
+		"Browser: ",
+		"PlatformName",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected '%s' in:\n%s", expected, out.String())
+		}
+	}
+
+	out.Reset()
+	diff(&out, manager, id, id)
+	if !strings.Contains(out.String(), "The device IDs have the same profiles.") {
+		t.Errorf("Expected the same profiles, but got:\n%s", out.String())
+	}
+
+	// Removing the browser profile only changes the browser
+	other := dd_example.DeviceId{
+		ProfileIds: append([]uint32(nil), id.ProfileIds...)}
+	other.ProfileIds[2] = 0
+	out.Reset()
+	diff(&out, manager, id, other)
+	if !strings.Contains(out.String(), "Browser: ") ||
+		strings.Contains(out.String(), "Hardware: ") {
+		t.Errorf("Expected only the browser to differ, but got:\n%s",
+			out.String())
+	}
+}
@@ -0,0 +1,108 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package dd_example
+
+/*
+This file contains a small curated corpus of Evidence Records embedded in the
+package, covering desktop, mobile, tablet, TV, crawler, client hint only and
+malformed evidence. It is used by tests when "20000 Evidence Records.yml" is
+not available, and by tools as a default set of labeled records.
+*/
+
+import (
+	"bytes"
+	_ "embed"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed fixtures/evidence.yml
+var fixtureEvidence []byte
+
+// Keys of the meta values of a fixture record
+const (
+	fixtureNameKey        = MetaPrefix + ".name"
+	fixtureExpectedPrefix = MetaPrefix + ".expected."
+)
+
+// Fixture is an Evidence Record of the embedded corpus and the expected
+// values of properties for it.
+type Fixture struct {
+	Name string
+	// Evidence Record as read from the file, including meta keys
+	Record map[string]string
+	// Expected values keyed by property name
+	Expected map[string]string
+}
+
+// FixtureEvidence returns a reader of the embedded corpus in the format of an
+// Evidence Records file.
+func FixtureEvidence() io.Reader {
+	return bytes.NewReader(fixtureEvidence)
+}
+
+// Fixtures returns the records of the embedded corpus.
+func Fixtures() ([]Fixture, error) {
+	return ReadFixtures(FixtureEvidence())
+}
+
+// ReadFixtures reads records in the format of the embedded corpus, i.e. an
+// Evidence Records file whose records may be named and have expected values.
+func ReadFixtures(r io.Reader) ([]Fixture, error) {
+	fixtures := make([]Fixture, 0)
+	dec := yaml.NewDecoder(r)
+	for {
+		var doc map[string]string
+		if err := dec.Decode(&doc); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		fixtures = append(fixtures, NewFixture(doc))
+	}
+	return fixtures, nil
+}
+
+// NewFixture returns the fixture of an Evidence Record, taking its name and
+// expected values from the meta keys.
+func NewFixture(record map[string]string) Fixture {
+	f := Fixture{
+		Name:     record[fixtureNameKey],
+		Record:   record,
+		Expected: make(map[string]string),
+	}
+	for k, v := range record {
+		if strings.HasPrefix(k, fixtureExpectedPrefix) {
+			f.Expected[strings.TrimPrefix(k, fixtureExpectedPrefix)] = v
+		}
+	}
+	return f
+}
+
+// ExpectedKey returns the key of the expected value of a property in an
+// Evidence Record.
+func ExpectedKey(property string) string {
+	return fixtureExpectedPrefix + property
+}
@@ -0,0 +1,107 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package dd_example
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/51Degrees/device-detection-examples-go/v4/dd/internal/testutil"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+// Test that the embedded fixtures are named, cover each kind of evidence and
+// only contain evidence which can be used by the engine.
+func TestFixtures(t *testing.T) {
+	fixtures, err := Fixtures()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[string]bool)
+	for _, f := range fixtures {
+		if f.Name == "" || names[f.Name] {
+			t.Errorf("Expected a unique name for fixture %v", f.Record)
+		}
+		names[f.Name] = true
+		for _, e := range ConvertEvidenceMap(f.Record) {
+			if e.Prefix != "header" && e.Prefix != "query" {
+				t.Errorf("Unexpected evidence '%s.%s' in fixture '%s'",
+					e.Prefix, e.Key, f.Name)
+			}
+		}
+	}
+	for _, kind := range []string{
+		"desktop", "mobile", "tablet", "tv", "crawler", "client-hints", "malformed",
+	} {
+		found := false
+		for name := range names {
+			found = found || strings.HasPrefix(name, kind)
+		}
+		if !found {
+			t.Errorf("Expected a fixture for '%s' evidence", kind)
+		}
+	}
+}
+
+// Test that the fixtures are detected with the expected values. Properties
+// which are not in the data file are not checked.
+func TestFixtureExpectedValues(t *testing.T) {
+	filePath := testutil.DataFilePathOrSkip(t)
+	CheckLeaks(t)
+	manager := NewTrackedManager()
+	config := dd.NewConfigHash(dd.Balanced)
+	if err := dd.InitManagerFromFile(
+		manager.ResourceManager, *config, "", filePath); err != nil {
+		t.Fatalf("Failed to initialize resource manager. %v", err)
+	}
+	defer manager.Free()
+
+	fixtures, err := Fixtures()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range fixtures {
+		evidence := ExtractEvidence(ConvertEvidenceMap(f.Record))
+		results := NewTrackedResults(
+			manager.ResourceManager, uint32(evidence.Count()), 0)
+		if err := results.MatchEvidence(evidence.Evidence); err != nil {
+			t.Errorf("%s: failed to perform detection. %v", f.Name, err)
+		}
+		for property, expected := range f.Expected {
+			v := GetPropertyValue(results.ResultsHash, property)
+			if v.Reason == ValueNotLoaded {
+				continue
+			}
+			if v.Value() != expected {
+				t.Errorf("%s: expected %s to be '%s', but got '%s'",
+					f.Name, property, expected, v)
+			}
+		}
+		if v := GetPropertyValue(results.ResultsHash, PropertyIsMobile); v.Reason == ValueError {
+			t.Errorf("%s: failed to get a value. %v", f.Name, v.Err)
+		}
+		results.Free()
+		evidence.Free()
+	}
+}
@@ -0,0 +1,366 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package main
+
+/*
+This program generates typed constants and accessor methods for the properties
+of a data file, so that a misspelt property name is a compile error rather
+than a missing value at runtime.
+
+For each property it emits a constant holding the property name, e.g.
+`PropertyIsMobile = "IsMobile"`, and a method of DeviceResults returning its
+value as a Go type, e.g. `func (d *DeviceResults) IsMobile() (bool, error)`.
+
+The Go API of the engine does not yet expose the type of a property, so the
+type is inferred from the values observed when detecting a set of sample
+evidence: the built-in sample User-Agents and, if given, the records of an
+Evidence Records file. A property is a:
+  - []string if any detection returns more than one value,
+  - Version if its name ends with "Version" and all values are version numbers,
+  - bool if all values are "True" or "False",
+  - int or float64 if all values are numbers,
+  - string otherwise, including when no value was observed.
+
+The properties used by the examples are generated into the dd_example package
+by the go:generate directive in device_results.go. To regenerate them after the
+data file changes, perform the following command in the `dd` directory:
+```
+go generate
+```
+
+To generate accessors for every property in a data file, perform:
+```
+go run ./gen_properties -d ../Enterprise-HashV41.hash -e "../20000 Evidence Records.yml" -o properties_gen.go
+```
+*/
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	dd_example "github.com/51Degrees/device-detection-examples-go/v4/dd"
+	"gopkg.in/yaml.v3"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+// Separator used to detect properties returning more than one value
+const separator = "\x1f"
+
+// Sample User-Agents covering common device types, used to observe the
+// values of each property.
+var sampleUserAgents = []string{
+	"Mozilla/5.0 (iPhone; CPU iPhone OS 7_1 like Mac OS X) AppleWebKit/537.51.2 (KHTML, like Gecko) Version/7.0 Mobile/11D167 Safari/9537.53",
+	"Mozilla/5.0 (Windows NT 6.3; WOW64; rv:41.0) Gecko/20100101 Firefox/41.0",
+	"Mozilla/5.0 (Linux; Android 4.4.2; X7 Quad Core Build/KOT49H) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/30.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/95.0.4638.69 Safari/537.36",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (iPad; CPU OS 15_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/15.0 Mobile/15E148 Safari/604.1",
+	"Mozilla/5.0 (Linux; Android 13; SM-S901B) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/112.0.0.0 Mobile Safari/537.36",
+	"Mozilla/5.0 (SMART-TV; Linux; Tizen 6.0) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/4.0 Chrome/76.0.3809.146 TV Safari/537.36",
+}
+
+// Names already used by DeviceResults which accessors must not replace
+var reservedNames = map[string]bool{
+	"Results": true,
+	"Strings": true,
+	"String":  true,
+	"Bool":    true,
+	"Int":     true,
+	"Float64": true,
+	"Version": true,
+}
+
+var versionPattern = regexp.MustCompile(`^\d+(\.\d+)*$`)
+
+// Getter of DeviceResults used for each type
+var getters = map[string]string{
+	"bool":     "Bool",
+	"int":      "Int",
+	"float64":  "Float64",
+	"string":   "String",
+	"[]string": "Strings",
+	"Version":  "Version",
+}
+
+// propertyInfo describes a property to generate an accessor for.
+type propertyInfo struct {
+	Name   string // Name of the property in the data file
+	Ident  string // Go identifier of the property
+	Type   string // Go type of the values
+	Getter string // Method of DeviceResults returning the type
+}
+
+// observation collects the values seen for a property to infer its type.
+type observation struct {
+	count      int
+	multiple   bool
+	allBool    bool
+	allInt     bool
+	allFloat   bool
+	allVersion bool
+}
+
+func newObservation() *observation {
+	return &observation{allBool: true, allInt: true, allFloat: true, allVersion: true}
+}
+
+// observe updates the observation with the values of a single detection.
+func (o *observation) observe(values []string) {
+	if len(values) > 1 {
+		o.multiple = true
+	}
+	for _, v := range values {
+		// Values with no meaningful value are ignored when inferring types, as
+		// the getters of DeviceResults return a NoValueError for them rather
+		// than failing to parse them.
+		if dd_example.IsUnknownValue(v) {
+			continue
+		}
+		o.count++
+		if v != "True" && v != "False" {
+			o.allBool = false
+		}
+		if _, err := strconv.Atoi(v); err != nil {
+			o.allInt = false
+		}
+		if _, err := strconv.ParseFloat(v, 64); err != nil {
+			o.allFloat = false
+		}
+		if !versionPattern.MatchString(v) {
+			o.allVersion = false
+		}
+	}
+}
+
+// inferType returns the Go type of a property from the values observed.
+func (o *observation) inferType(name string) string {
+	switch {
+	case o.count == 0:
+		return "string"
+	case o.multiple:
+		return "[]string"
+	case strings.HasSuffix(name, "Version") && o.allVersion:
+		return "Version"
+	case o.allBool:
+		return "bool"
+	case o.allInt:
+		return "int"
+	case o.allFloat:
+		return "float64"
+	}
+	return "string"
+}
+
+// identifier returns a valid exported Go identifier for a property name by
+// removing characters which cannot appear in one.
+func identifier(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	ident := b.String()
+	if ident == "" || !unicode.IsUpper([]rune(ident)[0]) {
+		ident = "P" + ident
+	}
+	return ident
+}
+
+// observeResults records the values of each property in the results of a
+// detection.
+func observeResults(results *dd.ResultsHash, observations map[string]*observation) {
+	for i, property := range results.AvailableProperties() {
+		hasValues, err := results.HasValuesByIndex(i)
+		if err != nil {
+			dd_example.Fatalln(err)
+		}
+		if !hasValues {
+			continue
+		}
+		value, err := results.ValuesString(property, separator)
+		if err != nil {
+			dd_example.Fatalln(err)
+		}
+		observations[property].observe(strings.Split(value, separator))
+	}
+}
+
+// collect detects the sample evidence and returns the properties available
+// in the manager with their inferred types, sorted by name.
+func collect(
+	manager *dd.ResourceManager,
+	evidenceFilePath string,
+	maxRecords int) []propertyInfo {
+	results := dd_example.NewTrackedResults(manager, 1, 0)
+	defer results.Free()
+
+	observations := make(map[string]*observation)
+	for _, property := range results.AvailableProperties() {
+		observations[property] = newObservation()
+	}
+
+	for _, ua := range sampleUserAgents {
+		if err := results.MatchUserAgent(ua); err != nil {
+			dd_example.Fatalln(err)
+		}
+		observeResults(results.ResultsHash, observations)
+	}
+
+	if evidenceFilePath != "" {
+		file, err := os.Open(evidenceFilePath)
+		if err != nil {
+			dd_example.Fatalf("ERROR: Failed to open file \"%s\".\n", evidenceFilePath)
+		}
+		defer file.Close()
+		dec := yaml.NewDecoder(file)
+		for i := 0; i < maxRecords; i++ {
+			var doc map[string]string
+			if err := dec.Decode(&doc); err == io.EOF {
+				break
+			} else if err != nil {
+				dd_example.Fatalf("ERROR: Failed during decoding file \"%s\". %v\n", evidenceFilePath, err)
+			}
+			evidence := dd_example.ExtractEvidence(dd_example.ConvertEvidenceMap(doc))
+			evResults := dd_example.NewTrackedResults(manager, uint32(evidence.Count()), 0)
+			if err := evResults.MatchEvidence(evidence.Evidence); err != nil {
+				dd_example.Fatalln(err)
+			}
+			observeResults(evResults.ResultsHash, observations)
+			evResults.Free()
+			evidence.Free()
+		}
+	}
+
+	props := make([]propertyInfo, 0, len(observations))
+	idents := make(map[string]string)
+	for name, o := range observations {
+		ident := identifier(name)
+		if reservedNames[ident] {
+			log.Printf("Skipping property '%s' as '%s' is reserved.\n", name, ident)
+			continue
+		}
+		if other, ok := idents[ident]; ok {
+			log.Printf("Skipping property '%s' as '%s' is already used by '%s'.\n",
+				name, ident, other)
+			continue
+		}
+		idents[ident] = name
+		t := o.inferType(name)
+		props = append(props, propertyInfo{name, ident, t, getters[t]})
+	}
+	sort.Slice(props, func(i, j int) bool { return props[i].Ident < props[j].Ident })
+	return props
+}
+
+var fileTemplate = template.Must(template.New("properties").Parse(
+	`// Code generated by gen_properties from {{.Source}}. DO NOT EDIT.
+
+package {{.Package}}
+
+// Names of the properties in the data file.
+const (
+{{- range .Properties}}
+	Property{{.Ident}} = {{printf "%q" .Name}}
+{{- end}}
+)
+{{range .Properties}}
+// {{.Ident}} returns the value of the {{.Name}} property.
+func (d *DeviceResults) {{.Ident}}() ({{.Type}}, error) {
+	return d.{{.Getter}}(Property{{.Ident}})
+}
+{{end}}`))
+
+// render returns the formatted Go source of the constants and accessors for
+// the properties.
+func render(pkg string, source string, props []propertyInfo) ([]byte, error) {
+	var buf bytes.Buffer
+	err := fileTemplate.Execute(&buf, struct {
+		Package    string
+		Source     string
+		Properties []propertyInfo
+	}{pkg, source, props})
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+func main() {
+	defer dd_example.ReportLeaksAtExit()
+
+	var dataFilePath, evidenceFilePath, outputPath, pkg, properties string
+	var maxRecords int
+	flag.StringVar(&dataFilePath, "data-file", "../"+dd_example.LiteDataFile, "Path to a 51Degrees Hash data file")
+	flag.StringVar(&dataFilePath, "d", dataFilePath, "Alias for -data-file")
+	flag.StringVar(&evidenceFilePath, "evidence-file", "", "Path to a Evidence Records YAML file used to infer property types")
+	flag.StringVar(&evidenceFilePath, "e", evidenceFilePath, "Alias for -evidence-file")
+	flag.IntVar(&maxRecords, "records", 1000, "Maximum number of Evidence Records used to infer property types")
+	flag.StringVar(&outputPath, "o", "properties_gen.go", "Path of the generated Go source file")
+	flag.StringVar(&pkg, "package", "dd_example", "Package of the generated Go source file")
+	flag.StringVar(&properties, "properties", "", "Comma separated properties to generate. All properties if empty")
+	flag.Parse()
+
+	dataFilePath = dd_example.GetFilePathByPath(dataFilePath)
+	if evidenceFilePath != "" {
+		evidenceFilePath = dd_example.GetFilePathByPath(evidenceFilePath)
+	}
+
+	// Initialise manager with the properties to generate
+	manager := dd_example.NewTrackedManager()
+	config := dd.NewConfigHash(dd.Balanced)
+	err := dd.InitManagerFromFile(
+		manager.ResourceManager,
+		*config,
+		properties,
+		dataFilePath)
+	if err != nil {
+		dd_example.Fatalln(err)
+	}
+
+	// Make sure manager object will be freed after the function execution
+	defer manager.Free()
+
+	props := collect(manager.ResourceManager, evidenceFilePath, maxRecords)
+	src, err := render(pkg, filepath.Base(dataFilePath), props)
+	if err != nil {
+		dd_example.Fatalf("ERROR: Failed to generate source. %v\n", err)
+	}
+	if err := os.WriteFile(outputPath, src, 0644); err != nil {
+		dd_example.Fatalf("ERROR: Failed to write file \"%s\". %v\n", outputPath, err)
+	}
+	fmt.Printf("Generated %d properties to \"%s\".\n", len(props), outputPath)
+}
@@ -0,0 +1,80 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// Test that property types are inferred from the values observed.
+func TestInferType(t *testing.T) {
+	testData := []struct {
+		name     string
+		values   [][]string
+		expected string
+	}{
+		{"IsMobile", [][]string{{"True"}, {"False"}}, "bool"},
+		{"ScreenPixelsWidth", [][]string{{"640"}, {"Unknown"}, {"1080"}}, "int"},
+		{"ScreenInchesDiagonal", [][]string{{"5.5"}, {"10"}}, "float64"},
+		{"BrowserVersion", [][]string{{"124.0"}, {"41"}}, "Version"},
+		{"PlatformVersion", [][]string{{"14.4.1"}, {"Unknown"}}, "Version"},
+		{"HardwareName", [][]string{{"iPhone 5", "iPhone 5c"}, {"Desktop"}}, "[]string"},
+		{"BrowserName", [][]string{{"Chrome"}, {"41"}}, "string"},
+		{"Version", [][]string{{"1.0"}, {"beta"}}, "string"},
+		{"HardwareModel", [][]string{{"Unknown"}, {"N/A"}}, "string"},
+	}
+
+	for _, data := range testData {
+		o := newObservation()
+		for _, v := range data.values {
+			o.observe(v)
+		}
+		if actual := o.inferType(data.name); actual != data.expected {
+			t.Errorf("Expected '%s' to be '%s', but got '%s'",
+				data.name, data.expected, actual)
+		}
+	}
+}
+
+// Test that generated source is valid Go with an accessor per property.
+func TestRender(t *testing.T) {
+	props := []propertyInfo{
+		{"IsMobile", identifier("IsMobile"), "bool", getters["bool"]},
+		{"SetHeaderBrowserAccept-CH", identifier("SetHeaderBrowserAccept-CH"), "string", getters["string"]},
+	}
+	src, err := render("dd_example", "test.hash", props)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"// Code generated by gen_properties from test.hash. DO NOT EDIT.",
+		"PropertySetHeaderBrowserAcceptCH = \"SetHeaderBrowserAccept-CH\"",
+		"func (d *DeviceResults) IsMobile() (bool, error) {",
+		"return d.Bool(PropertyIsMobile)",
+	} {
+		if !strings.Contains(string(src), expected) {
+			t.Errorf("Expected generated source to contain '%s':\n%s", expected, src)
+		}
+	}
+}
@@ -44,7 +44,7 @@ func match(
 	// Perform detection
 	err := results.MatchUserAgent(ua)
 	if err != nil {
-		log.Fatalln(err)
+		dd_example.Fatalln(err)
 	}
 
 	propertyName := "IsMobile"
@@ -52,7 +52,7 @@ func match(
 	// If results has values for required property
 	hasValues, err := results.HasValues(propertyName)
 	if err != nil {
-		log.Fatalln(err)
+		dd_example.Fatalln(err)
 	}
 
 	returnStr := ""
@@ -64,7 +64,7 @@ func match(
 			propertyName,
 			",")
 		if err != nil {
-			log.Fatalln(err)
+			dd_example.Fatalln(err)
 		}
 		returnStr = fmt.Sprintf("\tIsMobile: %s\n", value)
 	}
@@ -74,24 +74,24 @@ func match(
 
 func runGettingStarted(perf dd.PerformanceProfile) string {
 	// Initialise manager
-	manager := dd.NewResourceManager()
+	manager := dd_example.NewTrackedManager()
 	config := dd.NewConfigHash(perf)
 	filePath := dd_example.GetFilePathByName([]string{dd_example.LiteDataFile})
 
-	err := dd.InitManagerFromFile(
-		manager,
+	err := dd_example.InitManagerFromFile(
+		manager.ResourceManager,
 		*config,
 		"",
 		filePath)
 	if err != nil {
-		log.Fatalln(err)
+		dd_example.Fatalln(err)
 	}
 
 	// Make sure manager object will be freed after the function execution
 	defer manager.Free()
 
 	// Create results
-	results := dd.NewResultsHash(manager, 1, 0)
+	results := dd_example.NewTrackedResults(manager.ResourceManager, 1, 0)
 
 	// Make sure results object is freed after function execution.
 	defer results.Free()
@@ -112,15 +112,15 @@ func runGettingStarted(perf dd.PerformanceProfile) string {
 
 	// Perform detection on mobile User-Agent
 	actual := fmt.Sprintf("Mobile User-Agent: %s\n", uaMobile)
-	actual += match(results, uaMobile)
+	actual += match(results.ResultsHash, uaMobile)
 
 	// Perform detection on desktop User-Agent
 	actual += fmt.Sprintf("\nDesktop User-Agent: %s\n", uaDesktop)
-	actual += match(results, uaDesktop)
+	actual += match(results.ResultsHash, uaDesktop)
 
 	// Perform detection on MediaHub User-Agent
 	actual += fmt.Sprintf("\nMediaHub User-Agent: %s\n", uaMediaHub)
-	actual += match(results, uaMediaHub)
+	actual += match(results.ResultsHash, uaMediaHub)
 
 	// Expected output
 	expected := "Mobile User-Agent: Mozilla/5.0 (iPhone; CPU iPhone OS 7_1 like Mac OS X) AppleWebKit/537.51.2 (KHTML, like Gecko) Version/7.0 Mobile/11D167 Safari/9537.53\n"
@@ -137,20 +137,11 @@ func runGettingStarted(perf dd.PerformanceProfile) string {
 		log.Println("")
 		log.Println("Actual:")
 		log.Println(actual)
-		log.Fatalln("Output does not match expected.")
+		dd_example.Fatalln("Output does not match expected.")
 	}
 	return actual
 }
 
 func main() {
 	dd_example.PerformExample(dd.Default, runGettingStarted)
-	// Output:
-	// Mobile User-Agent: Mozilla/5.0 (iPhone; CPU iPhone OS 7_1 like Mac OS X) AppleWebKit/537.51.2 (KHTML, like Gecko) Version/7.0 Mobile/11D167 Safari/9537.53
-	// 	IsMobile: True
-	//
-	// Desktop User-Agent: Mozilla/5.0 (Windows NT 6.3; WOW64; rv:41.0) Gecko/20100101 Firefox/41.0
-	// 	IsMobile: False
-	//
-	// MediaHub User-Agent: Mozilla/5.0 (Linux; Android 4.4.2; X7 Quad Core Build/KOT49H) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/30.0.0.0 Safari/537.36
-	// 	IsMobile: False
 }
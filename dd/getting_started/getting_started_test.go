@@ -0,0 +1,56 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package main
+
+import (
+	"testing"
+
+	dd_example "github.com/51Degrees/device-detection-examples-go/v4/dd"
+	"github.com/51Degrees/device-detection-examples-go/v4/dd/internal/testutil"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+func TestMain(m *testing.M) {
+	dd_example.EnableLeakTracking(true)
+	testutil.Main(m, dd_example.ReportLeaks)
+}
+
+// Expected output of the example
+const expectedOutput = "Mobile User-Agent: Mozilla/5.0 (iPhone; CPU iPhone OS 7_1 like Mac OS X) AppleWebKit/537.51.2 (KHTML, like Gecko) Version/7.0 Mobile/11D167 Safari/9537.53\n" +
+	"\tIsMobile: True\n" +
+	"\n" +
+	"Desktop User-Agent: Mozilla/5.0 (Windows NT 6.3; WOW64; rv:41.0) Gecko/20100101 Firefox/41.0\n" +
+	"\tIsMobile: False\n" +
+	"\n" +
+	"MediaHub User-Agent: Mozilla/5.0 (Linux; Android 4.4.2; X7 Quad Core Build/KOT49H) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/30.0.0.0 Safari/537.36\n" +
+	"\tIsMobile: True\n"
+
+// Test that the example outputs the expected values with each performance
+// profile. It is skipped if the data file is not available.
+func TestGettingStarted(t *testing.T) {
+	testutil.DataFilePathOrSkip(t)
+	for _, perf := range dd_example.ExampleProfiles(dd.Default) {
+		testutil.CheckOutput(t, expectedOutput, runGettingStarted(perf))
+	}
+}
@@ -0,0 +1,108 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+// Package testutil contains helpers shared by the tests of the examples. It is
+// only imported by tests, so the examples do not depend on package testing.
+//
+// It does not import dd_example, as the tests of dd_example use it.
+package testutil
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+// Names of the files used by the tests, as dd_example.LiteDataFile and
+// dd_example.EvidenceFileYaml
+const (
+	liteDataFile     = "51Degrees-LiteV4.1.hash"
+	evidenceFileYaml = "20000 Evidence Records.yml"
+)
+
+// How to get the files needed to run tests, given in skip messages
+const fetchAssetsHint = "Run 'pwsh ci/fetch-assets.ps1 .' from the root of " +
+	"the repository to download it."
+
+// findFile returns the path of a file found by searching the parent
+// directory, as the examples do.
+func findFile(name string) (string, error) {
+	return dd.GetFilePath("..", []string{name})
+}
+
+// DataFilePathOrSkip returns the path of the Lite data file, or skips the
+// test if it cannot be found.
+func DataFilePathOrSkip(t testing.TB) string {
+	t.Helper()
+	path, err := findFile(liteDataFile)
+	if err != nil {
+		t.Skipf("Skipping as data file \"%s\" is not available. %s",
+			liteDataFile, fetchAssetsHint)
+	}
+	return path
+}
+
+// EvidenceFilePathOrFixtures returns the path of the Evidence Records file if
+// it can be found, or otherwise of a file in a temporary directory of the test
+// containing the fixtures, e.g. dd_example.FixtureEvidence().
+func EvidenceFilePathOrFixtures(t testing.TB, fixtures io.Reader) string {
+	t.Helper()
+	if path, err := findFile(evidenceFileYaml); err == nil {
+		return path
+	}
+	path := filepath.Join(t.TempDir(), "evidence.yml")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := io.Copy(file, fixtures); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("Using embedded fixtures as \"%s\" is not available.",
+		evidenceFileYaml)
+	return path
+}
+
+// CheckOutput fails the test if the output of an example is not the expected
+// output, ignoring leading and trailing white space as Example tests do.
+func CheckOutput(t testing.TB, expected, actual string) {
+	t.Helper()
+	if strings.TrimSpace(actual) != strings.TrimSpace(expected) {
+		t.Errorf("Expected output:\n%s\nbut got:\n%s", expected, actual)
+	}
+}
+
+// Main runs the tests from TestMain, and fails if reportLeaks reports any
+// tracked handles which have not been freed by the end, e.g.
+// testutil.Main(m, dd_example.ReportLeaks).
+func Main(m *testing.M, reportLeaks func(w io.Writer) int) {
+	code := m.Run()
+	if reportLeaks(os.Stderr) > 0 && code == 0 {
+		code = 1
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,283 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package dd_example
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+// Maximum number of stack frames recorded for each allocation
+const leakStackDepth = 32
+
+// Handles allocated by the tracked constructors and not yet freed. Tracking
+// is enabled by passing 'track-leaks' to an example, or by EnableLeakTracking.
+var leaks = struct {
+	sync.Mutex
+	enabled bool
+	nextId  uint64
+	live    map[uint64]*trackedHandle
+}{
+	enabled: isFlagOn("track-leaks"),
+	live:    make(map[uint64]*trackedHandle),
+}
+
+// trackedHandle is the record of the allocation of a native handle.
+type trackedHandle struct {
+	id        uint64
+	kind      string
+	allocated time.Time
+	stack     []uintptr
+}
+
+// EnableLeakTracking turns the tracking of the handles allocated by the
+// tracked constructors on or off. Handles allocated while tracking was off are
+// never reported.
+func EnableLeakTracking(enabled bool) {
+	leaks.Lock()
+	defer leaks.Unlock()
+	leaks.enabled = enabled
+}
+
+// LeakTracking returns true if handles are being tracked.
+func LeakTracking() bool {
+	leaks.Lock()
+	defer leaks.Unlock()
+	return leaks.enabled
+}
+
+// track records the allocation of a handle, returning 0 if tracking is off.
+// The stack is recorded from the caller of the tracked constructor.
+func track(kind string) uint64 {
+	leaks.Lock()
+	defer leaks.Unlock()
+	if !leaks.enabled {
+		return 0
+	}
+	leaks.nextId++
+	h := &trackedHandle{
+		id:        leaks.nextId,
+		kind:      kind,
+		allocated: time.Now(),
+		stack:     make([]uintptr, leakStackDepth),
+	}
+	h.stack = h.stack[:runtime.Callers(3, h.stack)]
+	leaks.live[h.id] = h
+	return h.id
+}
+
+// untrack records that a handle has been freed.
+func untrack(id uint64) {
+	if id == 0 {
+		return
+	}
+	leaks.Lock()
+	defer leaks.Unlock()
+	delete(leaks.live, id)
+}
+
+// TrackedEvidence is evidence whose allocation is tracked until it is freed.
+type TrackedEvidence struct {
+	*dd.Evidence
+	id uint64
+}
+
+// NewTrackedEvidence wraps dd.NewEvidenceHash.
+func NewTrackedEvidence(capacity uint32) *TrackedEvidence {
+	return &TrackedEvidence{dd.NewEvidenceHash(capacity), track("Evidence")}
+}
+
+// Free frees the evidence and stops tracking it.
+func (e *TrackedEvidence) Free() {
+	untrack(e.id)
+	e.id = 0
+	e.Evidence.Free()
+}
+
+// TrackedResults are results whose allocation is tracked until they are
+// freed.
+type TrackedResults struct {
+	*dd.ResultsHash
+	id uint64
+}
+
+// NewTrackedResults wraps dd.NewResultsHash.
+func NewTrackedResults(
+	manager *dd.ResourceManager,
+	evidenceCapacity uint32,
+	overridesCapacity uint32) *TrackedResults {
+	return &TrackedResults{
+		dd.NewResultsHash(manager, evidenceCapacity, overridesCapacity),
+		track("ResultsHash")}
+}
+
+// Free frees the results and stops tracking them.
+func (r *TrackedResults) Free() {
+	untrack(r.id)
+	r.id = 0
+	r.ResultsHash.Free()
+}
+
+// TrackedManager is a resource manager whose allocation is tracked until it
+// is freed.
+type TrackedManager struct {
+	*dd.ResourceManager
+	id uint64
+}
+
+// NewTrackedManager wraps dd.NewResourceManager.
+func NewTrackedManager() *TrackedManager {
+	return &TrackedManager{dd.NewResourceManager(), track("ResourceManager")}
+}
+
+// Free frees the resource manager and stops tracking it.
+func (m *TrackedManager) Free() {
+	untrack(m.id)
+	m.id = 0
+	m.ResourceManager.Free()
+}
+
+// LeakedHandle is a tracked handle which has not been freed, with the stack
+// of the goroutine which allocated it.
+type LeakedHandle struct {
+	Kind  string
+	Age   time.Duration
+	Stack string
+	id    uint64
+}
+
+func (l LeakedHandle) String() string {
+	return fmt.Sprintf("%s allocated %v ago at:\n%s",
+		l.Kind, l.Age.Round(time.Millisecond), l.Stack)
+}
+
+// LiveHandles returns the tracked handles which have not been freed, in the
+// order they were allocated.
+func LiveHandles() []LeakedHandle {
+	return liveHandlesAfter(0)
+}
+
+// liveHandlesAfter returns the live handles allocated after the one with the
+// id given.
+func liveHandlesAfter(id uint64) []LeakedHandle {
+	leaks.Lock()
+	handles := make([]*trackedHandle, 0, len(leaks.live))
+	for _, h := range leaks.live {
+		if h.id > id {
+			handles = append(handles, h)
+		}
+	}
+	leaks.Unlock()
+
+	sort.Slice(handles, func(i, j int) bool {
+		return handles[i].id < handles[j].id
+	})
+	result := make([]LeakedHandle, len(handles))
+	for i, h := range handles {
+		result[i] = LeakedHandle{
+			Kind:  h.kind,
+			Age:   time.Since(h.allocated),
+			Stack: formatStack(h.stack),
+			id:    h.id,
+		}
+	}
+	return result
+}
+
+// lastHandleId returns the id of the last tracked handle allocated.
+func lastHandleId() uint64 {
+	leaks.Lock()
+	defer leaks.Unlock()
+	return leaks.nextId
+}
+
+// formatStack formats the stack frames as in a panic.
+func formatStack(stack []uintptr) string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(stack)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "\t%s\n\t\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+// ReportLeaks writes the tracked handles which have not been freed and
+// returns how many there are.
+func ReportLeaks(w io.Writer) int {
+	handles := LiveHandles()
+	if len(handles) > 0 {
+		fmt.Fprintf(w, "%d native handles have not been freed.\n", len(handles))
+		for _, h := range handles {
+			fmt.Fprintln(w, h)
+		}
+	}
+	return len(handles)
+}
+
+// ReportLeaksAtExit reports the handles which have not been freed to stderr
+// if tracking is enabled, so that the output of the example is unchanged.
+// Examples which do not use PerformExample defer it from main. As deferred
+// calls do not run on exit, examples exit on errors with Fatal, Fatalf or
+// Fatalln which call it.
+func ReportLeaksAtExit() {
+	if LeakTracking() {
+		ReportLeaks(os.Stderr)
+	}
+}
+
+// Fatal is log.Fatal, reporting the handles which have not been freed before
+// exiting as deferred calls such as ReportLeaksAtExit do not run on exit.
+func Fatal(v ...interface{}) {
+	log.Output(2, fmt.Sprint(v...))
+	ReportLeaksAtExit()
+	os.Exit(1)
+}
+
+// Fatalf is log.Fatalf, reporting the handles which have not been freed
+// before exiting.
+func Fatalf(format string, v ...interface{}) {
+	log.Output(2, fmt.Sprintf(format, v...))
+	ReportLeaksAtExit()
+	os.Exit(1)
+}
+
+// Fatalln is log.Fatalln, reporting the handles which have not been freed
+// before exiting.
+func Fatalln(v ...interface{}) {
+	log.Output(2, fmt.Sprintln(v...))
+	ReportLeaksAtExit()
+	os.Exit(1)
+}
@@ -0,0 +1,125 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package dd_example
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// Test that only handles which have not been freed are reported, with the
+// stack of their allocation.
+func TestReportLeaks(t *testing.T) {
+	enabled := LeakTracking()
+	EnableLeakTracking(true)
+	defer EnableLeakTracking(enabled)
+	start := lastHandleId()
+
+	freed := NewTrackedEvidence(1)
+	freed.Free()
+	leaked := ExtractEvidence([]stringEvidence{
+		{"header", "User-Agent", iPhoneUA}})
+	if count := leaked.Count(); count != 1 {
+		t.Errorf("Expected 1 evidence, but got %d", count)
+	}
+
+	handles := liveHandlesAfter(start)
+	if len(handles) != 1 {
+		t.Fatalf("Expected 1 leaked handle, but got %v", handles)
+	}
+	if handles[0].Kind != "Evidence" ||
+		!strings.Contains(handles[0].Stack, "TestReportLeaks") {
+		t.Errorf("Expected evidence allocated by the test, but got %v",
+			handles[0])
+	}
+	var b bytes.Buffer
+	if n := ReportLeaks(&b); n < 1 ||
+		!strings.Contains(b.String(), "have not been freed") {
+		t.Errorf("Expected leaks to be reported, but got %d:\n%s", n, b.String())
+	}
+
+	// Freeing twice must be safe
+	leaked.Free()
+	leaked.Free()
+	if handles := liveHandlesAfter(start); len(handles) != 0 {
+		t.Errorf("Expected no leaked handles, but got %v", handles)
+	}
+}
+
+// Test that nothing is tracked while tracking is off.
+func TestLeakTrackingDisabled(t *testing.T) {
+	enabled := LeakTracking()
+	EnableLeakTracking(false)
+	defer EnableLeakTracking(enabled)
+	start := lastHandleId()
+
+	evidence := NewTrackedEvidence(1)
+	defer evidence.Free()
+	if handles := liveHandlesAfter(start); len(handles) != 0 {
+		t.Errorf("Expected no tracked handles, but got %v", handles)
+	}
+}
+
+// Test that Fatalf reports leaks before exiting. It runs in a child process as
+// it exits.
+func TestFatalf(t *testing.T) {
+	if os.Getenv("LEAK_TRACKING_FATAL") == "1" {
+		EnableLeakTracking(true)
+		NewTrackedEvidence(1)
+		Fatalf("ERROR: Fatal %d", 1)
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestFatalf$")
+	cmd.Env = append(os.Environ(), "LEAK_TRACKING_FATAL=1")
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatal("Expected the child process to exit with an error")
+	}
+	if !strings.Contains(string(out), "ERROR: Fatal 1\n") ||
+		!strings.Contains(string(out), "1 native handles have not been freed") ||
+		!strings.Contains(string(out), "TestFatalf") {
+		t.Errorf("Expected leaks to be reported, but got:\n%s", out)
+	}
+}
+
+// CheckLeaks tracks the handles allocated by the tracked constructors during
+// the test, and fails the test if any of them has not been freed when it ends.
+// Handles allocated by other goroutines are included, so it should not be used
+// by tests which run in parallel.
+func CheckLeaks(t testing.TB) {
+	t.Helper()
+	enabled := LeakTracking()
+	EnableLeakTracking(true)
+	start := lastHandleId()
+	t.Cleanup(func() {
+		EnableLeakTracking(enabled)
+		for _, h := range liveHandlesAfter(start) {
+			t.Errorf("Leaked %s", h)
+			// Only report each leak once
+			untrack(h.id)
+		}
+	})
+}
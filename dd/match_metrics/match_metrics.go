@@ -53,7 +53,7 @@ func matchMetrics(
 	// Perform detection
 	err := results.MatchUserAgent(ua)
 	if err != nil {
-		log.Fatalln(err)
+		dd_example.Fatalln(err)
 	}
 
 	propertyName := "IsMobile"
@@ -63,12 +63,12 @@ func matchMetrics(
 		propertyName,
 		",")
 	if err != nil {
-		log.Fatalln(err)
+		dd_example.Fatalln(err)
 	}
 
 	hasValues, err := results.HasValues(propertyName)
 	if err != nil {
-		log.Fatalln(err)
+		dd_example.Fatalln(err)
 	}
 
 	returnStr := ""
@@ -77,28 +77,17 @@ func matchMetrics(
 	} else {
 		deviceId, err := results.DeviceId()
 		if err != nil {
-			log.Fatalln(err)
+			dd_example.Fatalln(err)
 		}
 
 		drift := results.Drift()
 		difference := results.Difference()
 		iterations := results.Iterations()
-		method := results.Method()
-		methodStr := "NONE"
-		switch method {
-		case dd.Performance:
-			methodStr = "PERFORMANCE"
-		case dd.Combined:
-			methodStr = "COMBINED"
-		case dd.Predictive:
-			methodStr = "PREDICTIVE"
-		default:
-			methodStr = "NONE"
-		}
+		methodStr := dd_example.MatchMethodName(results.Method())
 		// We only use one User-Agent so there can only be one result
 		matchedUserAgent, err := results.UserAgent(0)
 		if err != nil {
-			log.Fatal(err.Error())
+			dd_example.Fatal(err.Error())
 		}
 
 		returnStr = fmt.Sprintf("\tIsMobile: %s\n", value)
@@ -128,7 +117,7 @@ func verifyOutputFormat(matchReport string) string {
 		log.Println("")
 		log.Println("Actual:")
 		log.Println(matchReport)
-		log.Fatalln("Output does not match expected.")
+		dd_example.Fatalln("Output does not match expected.")
 	}
 	return "Match metrics in format:\n" + readableFormat
 }
@@ -138,24 +127,24 @@ func verifyOutputFormat(matchReport string) string {
 
 func runMatchMetrics(perf dd.PerformanceProfile) string {
 	// Initialise manager
-	manager := dd.NewResourceManager()
+	manager := dd_example.NewTrackedManager()
 	config := dd.NewConfigHash(perf)
 	filePath := dd_example.GetFilePathByName([]string{dd_example.LiteDataFile})
 
-	err := dd.InitManagerFromFile(
-		manager,
+	err := dd_example.InitManagerFromFile(
+		manager.ResourceManager,
 		*config,
 		"ScreenPixelsWidth,HardwareModel,IsMobile,BrowserName,Id",
 		filePath)
 	if err != nil {
-		log.Fatalln(err)
+		dd_example.Fatalln(err)
 	}
 
 	// Make sure manager object will be freed after the function execution
 	defer manager.Free()
 
 	// Create results
-	results := dd.NewResultsHash(manager, 1, 0)
+	results := dd_example.NewTrackedResults(manager.ResourceManager, 1, 0)
 
 	// Make sure results object is freed after function execution
 	defer results.Free()
@@ -176,17 +165,17 @@ func runMatchMetrics(perf dd.PerformanceProfile) string {
 
 	// Carries out a match for a mobile User-Agent.
 	report := fmt.Sprintf("Mobile User-Agent: %s\n", uaMobile)
-	actual := matchMetrics(results, uaMobile)
+	actual := matchMetrics(results.ResultsHash, uaMobile)
 	report += verifyOutputFormat(actual)
 
 	// Carries out a match for a desktop User-Agent.
 	report += fmt.Sprintf("\nDesktop User-Agent: %s\n", uaDesktop)
-	actual = matchMetrics(results, uaDesktop)
+	actual = matchMetrics(results.ResultsHash, uaDesktop)
 	report += verifyOutputFormat(actual)
 
 	// Carries out a match for a MediaHub User-Agent.
 	report += fmt.Sprintf("\nMediaHub User-Agent: %s\n", uaMediaHub)
-	actual = matchMetrics(results, uaMediaHub)
+	actual = matchMetrics(results.ResultsHash, uaMediaHub)
 	report += verifyOutputFormat(actual)
 
 	return report
@@ -194,34 +183,4 @@ func runMatchMetrics(perf dd.PerformanceProfile) string {
 
 func main() {
 	dd_example.PerformExample(dd.Default, runMatchMetrics)
-	// Output:
-	// Mobile User-Agent: Mozilla/5.0 (iPhone; CPU iPhone OS 7_1 like Mac OS X) AppleWebKit/537.51.2 (KHTML, like Gecko) Version/7.0 Mobile/11D167 Safari/9537.53
-	// Match metrics in format:
-	//	IsMobile: [boolean]
-	//	Id: [number-number-number-number]
-	//	Drift: [number]
-	//	Difference: [number]
-	//	Iterations: [number]
-	//	Method: [PERFORMANCE|COMBINED|PREDICTIVE|NONE]
-	//	Sub strings: [string]
-	//
-	// Desktop User-Agent: Mozilla/5.0 (Windows NT 6.3; WOW64; rv:41.0) Gecko/20100101 Firefox/41.0
-	// Match metrics in format:
-	//	IsMobile: [boolean]
-	//	Id: [number-number-number-number]
-	//	Drift: [number]
-	//	Difference: [number]
-	//	Iterations: [number]
-	//	Method: [PERFORMANCE|COMBINED|PREDICTIVE|NONE]
-	//	Sub strings: [string]
-	//
-	// MediaHub User-Agent: Mozilla/5.0 (Linux; Android 4.4.2; X8 Quad Core Build/KOT49H) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/30.0.0.0 Safari/537.36
-	// Match metrics in format:
-	//	IsMobile: [boolean]
-	//	Id: [number-number-number-number]
-	//	Drift: [number]
-	//	Difference: [number]
-	//	Iterations: [number]
-	//	Method: [PERFORMANCE|COMBINED|PREDICTIVE|NONE]
-	//	Sub strings: [string]
 }
@@ -0,0 +1,408 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package main
+
+/*
+This example illustrates how to quantify the confidence of the detections made
+for a corpus of Evidence Records, such as traffic captured by the web examples.
+
+Every record is processed and the following is reported:
+  - the share of records matched by each method. NONE and PREDICTIVE matches
+    are the least confident,
+  - the distribution of Drift, Difference and Iterations,
+  - the records with the highest Difference,
+  - a breakdown of the above by DeviceType and BrowserName, or the properties
+    given by `-by`.
+
+Records deduplicated by evidence_corpus with `-count` are weighted by the
+number of times they occurred, so shares are of traffic rather than of
+distinct records.
+
+To run this example, perform the following command:
+```
+go run match_quality.go -e "../20000 Evidence Records.yml"
+```
+*/
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	dd_example "github.com/51Degrees/device-detection-examples-go/v4/dd"
+	"gopkg.in/yaml.v3"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+// Match methods in order of decreasing confidence
+var methods = []dd.MatchMethod{dd.Performance, dd.Combined, dd.Predictive, dd.None}
+
+type options struct {
+	DataFilePath     string
+	EvidenceFilePath string
+	Worst            int
+	By               string
+	showHelp         bool
+}
+
+func parseOptions() options {
+	o := options{}
+
+	flag.StringVar(&o.DataFilePath, "data-file", "../"+dd_example.LiteDataFile, "Path to a 51Degrees Hash data file")
+	flag.StringVar(&o.DataFilePath, "d", o.DataFilePath, "Alias for -data-file")
+
+	flag.StringVar(&o.EvidenceFilePath, "evidence-file", "../"+dd_example.EvidenceFileYaml, "Path to a Evidence Records YAML file")
+	flag.StringVar(&o.EvidenceFilePath, "e", o.EvidenceFilePath, "Alias for -evidence-file")
+
+	flag.IntVar(&o.Worst, "worst", 10, "Number of records with the highest Difference to report")
+	flag.StringVar(&o.By, "by", dd_example.PropertyDeviceType+","+dd_example.PropertyBrowserName, "Comma separated list of properties to break the metrics down by")
+
+	flag.BoolVar(&o.showHelp, "help", false, "Print help")
+	flag.BoolVar(&o.showHelp, "h", o.showHelp, "Alias for -help")
+
+	flag.Parse()
+	return o
+}
+
+// histogram counts the occurrences of each value of a metric.
+type histogram struct {
+	counts map[int32]uint64
+	total  uint64
+	sum    float64
+}
+
+func newHistogram() *histogram {
+	return &histogram{counts: make(map[int32]uint64)}
+}
+
+func (h *histogram) add(value int32, weight uint64) {
+	h.counts[value] += weight
+	h.total += weight
+	h.sum += float64(value) * float64(weight)
+}
+
+// percentile returns the lowest value which at least p percent of the
+// occurrences are less than or equal to.
+func (h *histogram) percentile(p float64) int32 {
+	values := make([]int32, 0, len(h.counts))
+	for v := range h.counts {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	var seen uint64
+	for _, v := range values {
+		seen += h.counts[v]
+		if float64(seen) >= p/100*float64(h.total) {
+			return v
+		}
+	}
+	return 0
+}
+
+func (h *histogram) mean() float64 {
+	if h.total == 0 {
+		return 0
+	}
+	return h.sum / float64(h.total)
+}
+
+// metrics of the detections for a group of records.
+type metrics struct {
+	records    uint64
+	methods    map[dd.MatchMethod]uint64
+	drift      *histogram
+	difference *histogram
+	iterations *histogram
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		methods:    make(map[dd.MatchMethod]uint64),
+		drift:      newHistogram(),
+		difference: newHistogram(),
+		iterations: newHistogram(),
+	}
+}
+
+func (m *metrics) add(d detection, weight uint64) {
+	m.records += weight
+	m.methods[d.method] += weight
+	m.drift.add(d.drift, weight)
+	m.difference.add(d.difference, weight)
+	m.iterations.add(d.iterations, weight)
+}
+
+// lowConfidence returns the share of records matched by the NONE or
+// PREDICTIVE methods.
+func (m *metrics) lowConfidence() float64 {
+	return share(m.methods[dd.None]+m.methods[dd.Predictive], m.records)
+}
+
+// detection is the match metrics and breakdown values of a single record.
+type detection struct {
+	userAgent  string
+	deviceId   string
+	method     dd.MatchMethod
+	drift      int32
+	difference int32
+	iterations int32
+	values     []string
+}
+
+// report of the match quality of a corpus.
+type report struct {
+	by     []string
+	all    *metrics
+	groups []map[string]*metrics
+	worst  []detection
+	nWorst int
+}
+
+func newReport(by []string, nWorst int) *report {
+	r := &report{by: by, all: newMetrics(), nWorst: nWorst}
+	r.groups = make([]map[string]*metrics, len(by))
+	for i := range by {
+		r.groups[i] = make(map[string]*metrics)
+	}
+	return r
+}
+
+func (r *report) add(d detection, weight uint64) {
+	r.all.add(d, weight)
+	for i, v := range d.values {
+		m, ok := r.groups[i][v]
+		if !ok {
+			m = newMetrics()
+			r.groups[i][v] = m
+		}
+		m.add(d, weight)
+	}
+
+	// Keep the records with the highest Difference, highest first
+	if r.nWorst <= 0 || d.difference == 0 {
+		return
+	}
+	i := sort.Search(len(r.worst), func(i int) bool {
+		return r.worst[i].difference < d.difference
+	})
+	if i < r.nWorst {
+		r.worst = append(r.worst, detection{})
+		copy(r.worst[i+1:], r.worst[i:])
+		r.worst[i] = d
+		if len(r.worst) > r.nWorst {
+			r.worst = r.worst[:r.nWorst]
+		}
+	}
+}
+
+// share returns n as a percentage of total.
+func share(n, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total) * 100
+}
+
+// detect performs a detection on a record and returns its metrics.
+func detect(
+	manager *dd.ResourceManager,
+	record map[string]string,
+	by []string) detection {
+	evidence := dd_example.ExtractEvidence(dd_example.ConvertEvidenceMap(record))
+	defer evidence.Free()
+	results := dd_example.NewTrackedResults(manager, uint32(evidence.Count()), 0)
+	defer results.Free()
+
+	if err := results.MatchEvidence(evidence.Evidence); err != nil {
+		dd_example.Fatalf("ERROR: Failed to perform detection. %v\n", err)
+	}
+	deviceId, err := results.DeviceId()
+	if err != nil {
+		dd_example.Fatalf("ERROR: Failed to get device id. %v\n", err)
+	}
+	d := detection{
+		userAgent:  record["header.user-agent"],
+		deviceId:   deviceId,
+		method:     results.Method(),
+		drift:      results.Drift(),
+		difference: results.Difference(),
+		iterations: results.Iterations(),
+		values:     make([]string, len(by)),
+	}
+	for i, property := range by {
+		d.values[i] = dd_example.GetPropertyValue(results.ResultsHash, property).String()
+	}
+	return d
+}
+
+// analyse processes every record of an Evidence Records file.
+func analyse(
+	manager *dd.ResourceManager,
+	evidenceFilePath string,
+	by []string,
+	nWorst int) *report {
+	file, err := os.Open(evidenceFilePath)
+	if err != nil {
+		dd_example.Fatalf("ERROR: Failed to open file \"%s\".\n", evidenceFilePath)
+	}
+	defer file.Close()
+
+	r := newReport(by, nWorst)
+	dec := yaml.NewDecoder(file)
+	for {
+		// Decode Evidence file by line
+		var doc map[string]string
+		if err := dec.Decode(&doc); err == io.EOF {
+			break
+		} else if err != nil {
+			dd_example.Fatalf("ERROR: Failed during decoding file \"%s\". %v\n", evidenceFilePath, err)
+		}
+		r.add(detect(manager, doc, by), dd_example.RecordCount(doc))
+	}
+	return r
+}
+
+// print writes the report in human readable form.
+func (r *report) print(w io.Writer) {
+	fmt.Fprintf(w, "Records: %d\n", r.all.records)
+	fmt.Fprintf(w, "Low confidence (NONE or PREDICTIVE): %.2f%%\n\n",
+		r.all.lowConfidence())
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Method\tRecords\tShare\t")
+	for _, method := range methods {
+		fmt.Fprintf(tw, "%s\t%d\t%.2f%%\t\n",
+			dd_example.MatchMethodName(method),
+			r.all.methods[method],
+			share(r.all.methods[method], r.all.records))
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Metric\tMean\tp50\tp90\tp99\tMax\t")
+	for _, m := range []struct {
+		name string
+		h    *histogram
+	}{
+		{"Drift", r.all.drift},
+		{"Difference", r.all.difference},
+		{"Iterations", r.all.iterations},
+	} {
+		fmt.Fprintf(tw, "%s\t%.2f\t%d\t%d\t%d\t%d\t\n",
+			m.name,
+			m.h.mean(),
+			m.h.percentile(50),
+			m.h.percentile(90),
+			m.h.percentile(99),
+			m.h.percentile(100))
+	}
+	tw.Flush()
+
+	if len(r.worst) > 0 {
+		fmt.Fprintf(w, "\nHighest Difference:\n")
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "Difference\tDrift\tMethod\tDevice Id\tUser-Agent\t")
+		for _, d := range r.worst {
+			fmt.Fprintf(tw, "%d\t%d\t%s\t%s\t%s\t\n",
+				d.difference,
+				d.drift,
+				dd_example.MatchMethodName(d.method),
+				d.deviceId,
+				d.userAgent)
+		}
+		tw.Flush()
+	}
+
+	for i, property := range r.by {
+		// Most common values first
+		values := make([]string, 0, len(r.groups[i]))
+		for v := range r.groups[i] {
+			values = append(values, v)
+		}
+		sort.Slice(values, func(a, b int) bool {
+			ma, mb := r.groups[i][values[a]], r.groups[i][values[b]]
+			if ma.records != mb.records {
+				return ma.records > mb.records
+			}
+			return values[a] < values[b]
+		})
+
+		fmt.Fprintf(w, "\nBy %s:\n", property)
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "Value\tRecords\tShare\tNONE\tPREDICTIVE\tMean Difference\tMean Drift\t")
+		for _, v := range values {
+			m := r.groups[i][v]
+			fmt.Fprintf(tw, "%s\t%d\t%.2f%%\t%.2f%%\t%.2f%%\t%.2f\t%.2f\t\n",
+				v,
+				m.records,
+				share(m.records, r.all.records),
+				share(m.methods[dd.None], m.records),
+				share(m.methods[dd.Predictive], m.records),
+				m.difference.mean(),
+				m.drift.mean())
+		}
+		tw.Flush()
+	}
+}
+
+func main() {
+	defer dd_example.ReportLeaksAtExit()
+
+	o := parseOptions()
+	if o.showHelp {
+		flag.Usage()
+		return
+	}
+
+	by := make([]string, 0)
+	for _, p := range strings.Split(o.By, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			by = append(by, p)
+		}
+	}
+
+	// Initialise manager
+	manager := dd_example.NewTrackedManager()
+	config := dd.NewConfigHash(dd.Balanced)
+	filePath := dd_example.GetFilePathByPath(o.DataFilePath)
+	err := dd.InitManagerFromFile(
+		manager.ResourceManager,
+		*config,
+		strings.Join(by, ","),
+		filePath)
+	if err != nil {
+		dd_example.Fatalln(err)
+	}
+
+	// Make sure manager object will be freed after the function execution
+	defer manager.Free()
+
+	evidenceFilePath := dd_example.GetFilePathByPath(o.EvidenceFilePath)
+	analyse(manager.ResourceManager, evidenceFilePath, by, o.Worst).print(os.Stdout)
+}
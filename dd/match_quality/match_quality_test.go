@@ -0,0 +1,92 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package main
+
+import (
+	"testing"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+// Test that percentiles account for the weight of each value.
+func TestHistogramPercentile(t *testing.T) {
+	h := newHistogram()
+	h.add(0, 90)
+	h.add(5, 9)
+	h.add(40, 1)
+
+	testData := []struct {
+		p        float64
+		expected int32
+	}{
+		{50, 0},
+		{90, 0},
+		{95, 5},
+		{99, 5},
+		{100, 40},
+	}
+	for _, data := range testData {
+		if actual := h.percentile(data.p); actual != data.expected {
+			t.Errorf("Expected p%v to be %d, but got %d", data.p, data.expected, actual)
+		}
+	}
+	if mean := h.mean(); mean != 0.85 {
+		t.Errorf("Expected mean to be 0.85, but got %v", mean)
+	}
+}
+
+// Test that the report keeps the records with the highest Difference and
+// breaks the methods down by property value.
+func TestReport(t *testing.T) {
+	r := newReport([]string{"DeviceType"}, 2)
+	for _, d := range []struct {
+		difference int32
+		method     dd.MatchMethod
+		deviceType string
+		weight     uint64
+	}{
+		{0, dd.Performance, "Desktop", 6},
+		{3, dd.Predictive, "Mobile", 1},
+		{9, dd.None, "Mobile", 1},
+		{5, dd.Predictive, "Mobile", 2},
+	} {
+		r.add(detection{
+			difference: d.difference,
+			method:     d.method,
+			values:     []string{d.deviceType},
+		}, d.weight)
+	}
+
+	if r.all.records != 10 {
+		t.Errorf("Expected 10 records, but got %d", r.all.records)
+	}
+	if len(r.worst) != 2 || r.worst[0].difference != 9 || r.worst[1].difference != 5 {
+		t.Errorf("Expected the highest Differences to be 9 and 5, but got %v", r.worst)
+	}
+	if low := r.all.lowConfidence(); low != 40 {
+		t.Errorf("Expected 40%% low confidence, but got %v", low)
+	}
+	if low := r.groups[0]["Mobile"].lowConfidence(); low != 100 {
+		t.Errorf("Expected 100%% low confidence for Mobile, but got %v", low)
+	}
+}
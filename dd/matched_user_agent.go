@@ -0,0 +1,151 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package dd_example
+
+/*
+This file contains renderers which show the parts of a User-Agent that were
+matched by a detection. When the resource manager is initialised with
+SetUpdateMatchedUserAgent(true), results.UserAgent(0) is the User-Agent with
+every byte which was not part of a matched substring replaced by '_', so it
+is aligned with the input byte by byte. A character of more than one byte in
+UTF-8 is only matched if all of its bytes were, so segments never split one.
+*/
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// ANSI escape codes used to highlight matched and unmatched characters
+const (
+	ansiMatched   = "\x1b[32m"
+	ansiUnmatched = "\x1b[31m"
+	ansiReset     = "\x1b[0m"
+)
+
+// MatchSegment is a run of characters of a User-Agent which were all matched
+// or all not matched.
+type MatchSegment struct {
+	Text    string
+	Matched bool
+}
+
+// AlignMatchedUserAgent splits the input User-Agent into segments of matched
+// and unmatched characters by comparing it with the matched User-Agent of a
+// detection. Characters beyond the length of the matched User-Agent, which is
+// limited by the engine, are not matched. A '_' in the input is treated as
+// matched if the matched User-Agent also has a '_' in that position.
+func AlignMatchedUserAgent(input, matched string) []MatchSegment {
+	segments := make([]MatchSegment, 0)
+	var b strings.Builder
+	current := false
+	for i := 0; i < len(input); {
+		// Invalid UTF-8 is taken a byte at a time
+		_, size := utf8.DecodeRuneInString(input[i:])
+		m := matchedBytes(input[i:i+size], matched, i)
+		if m != current && b.Len() > 0 {
+			segments = append(segments, MatchSegment{b.String(), current})
+			b.Reset()
+		}
+		current = m
+		b.WriteString(input[i : i+size])
+		i += size
+	}
+	if b.Len() > 0 {
+		segments = append(segments, MatchSegment{b.String(), current})
+	}
+	return segments
+}
+
+// matchedBytes returns true if every byte of a character of the input at an
+// offset was matched.
+func matchedBytes(char, matched string, offset int) bool {
+	if offset+len(char) > len(matched) {
+		return false
+	}
+	return matched[offset:offset+len(char)] == char
+}
+
+// MatchedShare returns the share of the characters of the segments which were
+// matched, between 0 and 1.
+func MatchedShare(segments []MatchSegment) float64 {
+	matched, total := 0, 0
+	for _, s := range segments {
+		n := utf8.RuneCountInString(s.Text)
+		total += n
+		if s.Matched {
+			matched += n
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(matched) / float64(total)
+}
+
+// RenderMatchANSI returns the User-Agent with matched characters in green and
+// unmatched characters in red for display in a terminal.
+func RenderMatchANSI(segments []MatchSegment) string {
+	var b strings.Builder
+	for _, s := range segments {
+		if s.Matched {
+			b.WriteString(ansiMatched)
+		} else {
+			b.WriteString(ansiUnmatched)
+		}
+		b.WriteString(s.Text)
+	}
+	b.WriteString(ansiReset)
+	return b.String()
+}
+
+// RenderMatchMarkers returns a line to print below the User-Agent with '^'
+// under each matched character, for output which does not support colour.
+func RenderMatchMarkers(segments []MatchSegment) string {
+	var b strings.Builder
+	for _, s := range segments {
+		marker := " "
+		if s.Matched {
+			marker = "^"
+		}
+		b.WriteString(strings.Repeat(marker, utf8.RuneCountInString(s.Text)))
+	}
+	return strings.TrimRight(b.String(), " ")
+}
+
+// RenderMatchHTML returns the User-Agent as HTML with each segment in a span
+// of class "matched" or "unmatched".
+func RenderMatchHTML(segments []MatchSegment) string {
+	var b strings.Builder
+	for _, s := range segments {
+		class := "unmatched"
+		if s.Matched {
+			class = "matched"
+		}
+		b.WriteString(`<span class="` + class + `">`)
+		b.WriteString(html.EscapeString(s.Text))
+		b.WriteString("</span>")
+	}
+	return b.String()
+}
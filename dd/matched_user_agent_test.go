@@ -0,0 +1,112 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package dd_example
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Test that the input is split into matched and unmatched segments.
+func TestAlignMatchedUserAgent(t *testing.T) {
+	testData := []struct {
+		input    string
+		matched  string
+		expected []MatchSegment
+	}{
+		{
+			"Mozilla/5.0 (iPhone; CPU",
+			"_____________iPhone; ___",
+			[]MatchSegment{
+				{"Mozilla/5.0 (", false},
+				{"iPhone; ", true},
+				{"CPU", false},
+			},
+		},
+		{
+			"a_b",
+			"a_b",
+			[]MatchSegment{{"a_b", true}},
+		},
+		{
+			"abc",
+			"",
+			[]MatchSegment{{"abc", false}},
+		},
+		{"", "", []MatchSegment{}},
+		{
+			// Characters of more than one byte are matched as a whole
+			"Ünïcode/1",
+			"\xc3\x9c___code/1",
+			[]MatchSegment{{"Ü", true}, {"nï", false}, {"code/1", true}},
+		},
+		{
+			"aéb",
+			"a\xc3_b",
+			[]MatchSegment{{"a", true}, {"é", false}, {"b", true}},
+		},
+		{
+			"aé",
+			"a\xc3",
+			[]MatchSegment{{"a", true}, {"é", false}},
+		},
+	}
+	for _, data := range testData {
+		actual := AlignMatchedUserAgent(data.input, data.matched)
+		if !reflect.DeepEqual(actual, data.expected) {
+			t.Errorf("Expected '%s' to align as %v, but got %v",
+				data.input, data.expected, actual)
+		}
+	}
+}
+
+// Test the renderers of aligned segments.
+func TestRenderMatch(t *testing.T) {
+	segments := []MatchSegment{{"<a ", false}, {"bc", true}, {"d", false}}
+
+	if share := MatchedShare(segments); share != 2.0/6.0 {
+		t.Errorf("Expected a share of 2/6, but got %v", share)
+	}
+	if m := RenderMatchMarkers(segments); m != "   ^^" {
+		t.Errorf("Expected markers '   ^^', but got '%s'", m)
+	}
+	// Markers are aligned with characters rather than bytes
+	segments = []MatchSegment{{"aé", true}, {"ü", false}, {"b", true}}
+	if share := MatchedShare(segments); share != 3.0/4.0 {
+		t.Errorf("Expected a share of 3/4, but got %v", share)
+	}
+	if m := RenderMatchMarkers(segments); m != "^^ ^" {
+		t.Errorf("Expected markers '^^ ^', but got '%s'", m)
+	}
+
+	segments = []MatchSegment{{"<a ", false}, {"bc", true}, {"d", false}}
+	expected := `<span class="unmatched">&lt;a </span>` +
+		`<span class="matched">bc</span><span class="unmatched">d</span>`
+	if h := RenderMatchHTML(segments); h != expected {
+		t.Errorf("Expected '%s', but got '%s'", expected, h)
+	}
+	expected = "\x1b[31m<a \x1b[32mbc\x1b[31md\x1b[0m"
+	if a := RenderMatchANSI(segments); a != expected {
+		t.Errorf("Expected %q, but got %q", expected, a)
+	}
+}
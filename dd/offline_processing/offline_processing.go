@@ -27,13 +27,11 @@ This example illustrates how to process a list of Evidence Records from file and
 output detection metrics and properties of each Evidence Record to another file for
 further evaluation.
 
-Expected output is as described at the "// Output:..." section locate at the
-bottom of this example.
-
 To run this example, perform the following command:
 ```
-go test -run Example_offline_processing
+go run offline_processing.go
 ```
+Its output is verified by TestOfflineProcessing, which is run with `go test`.
 
 This example will output to a file located at
 "../device-detection-go/dd/device-detection-cxx/device-detection-data/20000 Evidence Records.processed.yml".
@@ -43,7 +41,6 @@ This contains IsMobile, BrowserName, BrowserVersion, PlatformName, PlatformVersi
 import (
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
@@ -58,18 +55,18 @@ import (
 // configured properties and returns them as yaml entry
 func processEvidence(
 	manager *dd.ResourceManager,
-	evidence *dd.Evidence) map[string]string {
+	evidence *dd_example.TrackedEvidence) map[string]string {
 	defer evidence.Free()
 	// Create results
-	results := dd.NewResultsHash(manager, uint32(evidence.Count()), 0)
+	results := dd_example.NewTrackedResults(manager, uint32(evidence.Count()), 0)
 	// Make sure results object is freed after function execution.
 	defer results.Free()
 	available := results.AvailableProperties()
 
 	// Perform detection
-	err := results.MatchEvidence(evidence)
+	err := results.MatchEvidence(evidence.Evidence)
 	if err != nil {
-		log.Fatal("ERROR: Failed to perform detection.")
+		dd_example.Fatal("ERROR: Failed to perform detection.")
 	}
 
 	// Get the values in string
@@ -77,7 +74,7 @@ func processEvidence(
 	for i := 0; i < len(available); i++ {
 		hasValues, err := results.HasValuesByIndex(i)
 		if err != nil {
-			log.Fatalln(err)
+			dd_example.Fatalln(err)
 		}
 
 		lowerKey := strings.ToLower(available[i])
@@ -86,14 +83,14 @@ func processEvidence(
 				available[i],
 				",")
 			if err != nil {
-				log.Fatalln(err)
+				dd_example.Fatalln(err)
 			}
 			res["device."+lowerKey] = value
 		}
 	}
 	res["device.deviceid"], err = results.DeviceId()
 	if err != nil {
-		log.Fatalf("ERROR: Failed to get unique DeviceID: %v", err)
+		dd_example.Fatalf("ERROR: Failed to get unique DeviceID: %v", err)
 	}
 	return res
 }
@@ -104,22 +101,22 @@ func process(
 	outputFilePath string) {
 	outFile, err := os.Create(outputFilePath)
 	if err != nil {
-		log.Fatalf("ERROR: Failed to create file %s.\n", outputFilePath)
+		dd_example.Fatalf("ERROR: Failed to create file %s.\n", outputFilePath)
 	}
 	defer func() {
 		if err := outFile.Close(); err != nil {
-			log.Fatalf("ERROR: Failed to close file \"%s\".\n", outputFilePath)
+			dd_example.Fatalf("ERROR: Failed to close file \"%s\".\n", outputFilePath)
 		}
 	}()
 
 	// Open the Evidence Records file for processing
 	file, err := os.OpenFile(evidenceFilePath, os.O_RDONLY, 0444)
 	if err != nil {
-		log.Fatalf("ERROR: Failed to open file \"%s\".\n", evidenceFilePath)
+		dd_example.Fatalf("ERROR: Failed to open file \"%s\".\n", evidenceFilePath)
 	}
 	defer func() {
 		if err := file.Close(); err != nil {
-			log.Fatalf("ERROR: Failed to close file \"%s\".\n", evidenceFilePath)
+			dd_example.Fatalf("ERROR: Failed to close file \"%s\".\n", evidenceFilePath)
 		}
 	}()
 
@@ -131,7 +128,7 @@ func process(
 		if err := dec.Decode(&doc); err == io.EOF {
 			break
 		} else if err != nil {
-			log.Fatalf("ERROR: Failed during decoding file \"%s\". %v\n", evidenceFilePath, err)
+			dd_example.Fatalf("ERROR: Failed during decoding file \"%s\". %v\n", evidenceFilePath, err)
 		}
 
 		// Prepare evidence for usage
@@ -142,7 +139,7 @@ func process(
 
 		err = enc.Encode(values)
 		if err != nil {
-			log.Fatalf("ERROR: Failed during encoding file \"%s\". %v\n", outputFilePath, err)
+			dd_example.Fatalf("ERROR: Failed during encoding file \"%s\". %v\n", outputFilePath, err)
 		}
 	}
 	enc.Close()
@@ -150,51 +147,58 @@ func process(
 	// Manually writing '...' to end the YAML file
 	_, err = outFile.WriteString("...\n")
 	if err != nil {
-		log.Fatalf("ERROR: Failed to write end for file \"%s\". %v\n", outputFilePath, err)
+		dd_example.Fatalf("ERROR: Failed to write end for file \"%s\". %v\n", outputFilePath, err)
 	}
 }
 
 func runOfflineProcessing(perf dd.PerformanceProfile) string {
-	// Initialise manager
-	manager := dd.NewResourceManager()
-	config := dd.NewConfigHash(perf)
 	filePath := dd_example.GetFilePathByName([]string{dd_example.LiteDataFile})
 	evidenceFilePath := dd_example.GetFilePathByName([]string{dd_example.EvidenceFileYaml})
+	return processEvidenceFile(perf, filePath, evidenceFilePath)
+}
+
+// processEvidenceFile performs detections on the Evidence Records file and
+// outputs the results to a file next to it.
+func processEvidenceFile(
+	perf dd.PerformanceProfile,
+	filePath string,
+	evidenceFilePath string) string {
+	// Initialise manager
+	manager := dd_example.NewTrackedManager()
+	config := dd.NewConfigHash(perf)
 	evDir := filepath.Dir(evidenceFilePath)
 	evBase := strings.TrimSuffix(filepath.Base(evidenceFilePath), filepath.Ext(evidenceFilePath))
 	outputFilePath := fmt.Sprintf("%s/%s.processed.yml", evDir, evBase)
 	// Get base path
 	basePath, err := os.Getwd()
 	if err != nil {
-		log.Fatalln("Failed to get current directory.")
+		dd_example.Fatalln("Failed to get current directory.")
 	}
 	// Get relative output path for testing
 	relOutputFilePath, err := filepath.Rel(basePath, outputFilePath)
 	if err != nil {
-		log.Fatalln("Failed to get relative output file path.")
+		dd_example.Fatalln("Failed to get relative output file path.")
 	}
 	// Convert path separators to '/'
 	relOutputFilePath = filepath.ToSlash(relOutputFilePath)
 
 	config.SetUpdateMatchedUserAgent(true)
-	err = dd.InitManagerFromFile(
-		manager,
+	err = dd_example.InitManagerFromFile(
+		manager.ResourceManager,
 		*config,
 		"IsMobile,BrowserName,BrowserVersion,PlatformName,PlatformVersion",
 		filePath)
 	if err != nil {
-		log.Fatalln(err)
+		dd_example.Fatalln(err)
 	}
 
 	// Make sure manager object will be freed after the function execution
 	defer manager.Free()
 
-	process(manager, evidenceFilePath, outputFilePath)
+	process(manager.ResourceManager, evidenceFilePath, outputFilePath)
 	return fmt.Sprintf("Output to \"%s\".\n", relOutputFilePath)
 }
 
 func main() {
 	dd_example.PerformExample(dd.Default, runOfflineProcessing)
-	// Output:
-	// Output to "../20000 Evidence Records.processed.yml".
 }
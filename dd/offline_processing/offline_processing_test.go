@@ -0,0 +1,65 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	dd_example "github.com/51Degrees/device-detection-examples-go/v4/dd"
+	"github.com/51Degrees/device-detection-examples-go/v4/dd/internal/testutil"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+func TestMain(m *testing.M) {
+	dd_example.EnableLeakTracking(true)
+	testutil.Main(m, dd_example.ReportLeaks)
+}
+
+// Test that the example outputs a record for each Evidence Record with each
+// performance profile. The embedded fixtures are used if the Evidence Records
+// file is not available.
+func TestOfflineProcessing(t *testing.T) {
+	filePath := testutil.DataFilePathOrSkip(t)
+	evidenceFilePath := testutil.EvidenceFilePathOrFixtures(
+		t, dd_example.FixtureEvidence())
+	outputFilePath := strings.TrimSuffix(evidenceFilePath,
+		filepath.Ext(evidenceFilePath)) + ".processed.yml"
+	expected := dd_example.CountEvidenceFromFiles(evidenceFilePath)
+
+	for _, perf := range dd_example.ExampleProfiles(dd.Default) {
+		output := processEvidenceFile(perf, filePath, evidenceFilePath)
+		if !strings.HasPrefix(output, "Output to ") {
+			t.Errorf("Unexpected output '%s'", output)
+		}
+		if _, err := os.Stat(outputFilePath); err != nil {
+			t.Fatal(err)
+		}
+		if count := dd_example.CountEvidenceFromFiles(outputFilePath); count != expected {
+			t.Errorf("Expected '%d' records, but got '%d'", expected, count)
+		}
+	}
+}
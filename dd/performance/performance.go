@@ -25,13 +25,11 @@ package main
 /*
 This example illustrates the performance of 51Degrees device detection solution.
 
-Expected output is as described at the "// Output:..." section locate at the
-bottom of this example.
-
 To run this example, perform the following command:
 ```
-go test -run Example_performance
+go run performance.go
 ```
+Its output is verified by TestPerformance, which is run with `go test`.
 
 This example will output a report to ./performance_report.log. The report
 content is in the below format:
@@ -48,7 +46,6 @@ import ( //	"runtime"
 	"bufio"
 	"fmt"
 	"io"
-	"log"
 	_ "net/http/pprof"
 	"os"
 	"path/filepath"
@@ -79,20 +76,20 @@ type report struct {
 func matchEvidenceRecord(
 	wg *sync.WaitGroup,
 	manager *dd.ResourceManager,
-	evidence *dd.Evidence,
+	evidence *dd_example.TrackedEvidence,
 	rep *report) {
 	// Increase the number of Evidence Record being processed
 	atomic.AddUint64(&rep.evidenceProcessed, 1)
 
-	results := dd.NewResultsHash(manager, uint32(evidence.Count()), 0)
+	results := dd_example.NewTrackedResults(manager, uint32(evidence.Count()), 0)
 
 	// Make sure results object is freed after function execution.
 	defer results.Free()
 
 	// Perform detection
-	err := results.MatchEvidence(evidence)
+	err := results.MatchEvidence(evidence.Evidence)
 	if err != nil {
-		log.Fatal("ERROR: Failed to perform detection.")
+		dd_example.Fatal("ERROR: Failed to perform detection.")
 	}
 
 	// Get the value in string
@@ -100,7 +97,7 @@ func matchEvidenceRecord(
 		"IsMobile",
 		",")
 	if err != nil {
-		log.Fatalln(err)
+		dd_example.Fatalln(err)
 	}
 
 	// Update report
@@ -153,21 +150,21 @@ func performDetections(
 
 // Open, read, decode and extract Evidence to be used in the performance test.
 // Data can be reused for multiple iterations.
-func readYAMLFile(evidenceFilePath string) []*dd.Evidence {
+func readYAMLFile(evidenceFilePath string) []*dd_example.TrackedEvidence {
 	// Open YAML file
 	file, err := os.OpenFile(evidenceFilePath, os.O_RDONLY, 0444)
 	if err != nil {
-		log.Fatalf("ERROR: Failed to open file \"%s\".\n", evidenceFilePath)
+		dd_example.Fatalf("ERROR: Failed to open file \"%s\".\n", evidenceFilePath)
 	}
 	defer func() {
 		// Make sure the file is closed properly
 		if err := file.Close(); err != nil {
-			log.Fatalf("ERROR: Failed to close file \"%s\".\n", evidenceFilePath)
+			dd_example.Fatalf("ERROR: Failed to close file \"%s\".\n", evidenceFilePath)
 		}
 	}()
 
 	// Decode YAML file
-	var res []*dd.Evidence
+	var res []*dd_example.TrackedEvidence
 	dec := yaml.NewDecoder(file)
 	for {
 		// Decode Evidence file by line
@@ -176,7 +173,7 @@ func readYAMLFile(evidenceFilePath string) []*dd.Evidence {
 			break
 		} else if err != nil {
 			// Make sure there is no decoder error
-			log.Fatalf("ERROR: Failed during decoding file \"%s\". %v\n", evidenceFilePath, err)
+			dd_example.Fatalf("ERROR: Failed during decoding file \"%s\". %v\n", evidenceFilePath, err)
 		}
 		// Prepare evidence for usage
 		filteredEvidence := dd_example.ConvertEvidenceMap(doc)
@@ -190,7 +187,7 @@ func readYAMLFile(evidenceFilePath string) []*dd.Evidence {
 // Check a error returned from writing to a buffer
 func checkWriteError(err error) {
 	if err != nil {
-		log.Fatalln("ERROR: Failed to write to buffer.")
+		dd_example.Fatalln("ERROR: Failed to write to buffer.")
 	}
 }
 
@@ -203,7 +200,7 @@ func printReport(actR *report, logOutputPath string) string {
 	} else {
 		rootDir, e := os.Getwd()
 		if e != nil {
-			log.Fatalln("Failed to get current directory.")
+			dd_example.Fatalln("Failed to get current directory.")
 		}
 		path = filepath.Join(rootDir, logOutputPath)
 	}
@@ -212,7 +209,7 @@ func printReport(actR *report, logOutputPath string) string {
 	// Create a report file
 	f, err := os.Create(path)
 	if err != nil {
-		log.Fatalf("ERROR: Failed to create report file \"%s\".", path)
+		dd_example.Fatalf("ERROR: Failed to create report file \"%s\".", path)
 	}
 	defer f.Close()
 
@@ -246,7 +243,7 @@ func run(
 	performDetections(manager, options, &actReport)
 	// Validation to make sure same number of Evidences have been read and processed
 	if actReport.evidenceCount != actReport.evidenceProcessed {
-		log.Fatalln("ERROR: Not all Evidence Records have been processed.")
+		dd_example.Fatalln("ERROR: Not all Evidence Records have been processed.")
 	}
 
 	// Print the final performance report
@@ -259,27 +256,27 @@ func runPerformance(perf dd.PerformanceProfile, options dd_example.Options) stri
 	dataFilePath := dd_example.GetFilePathByPath(options.DataFilePath)
 
 	// Create Resource Manager
-	manager := dd.NewResourceManager()
+	manager := dd_example.NewTrackedManager()
 	config := dd.NewConfigHash(dd.InMemory)
 	config.SetConcurrency(uint16(runtime.NumCPU()))
 	config.SetUsePredictiveGraph(false)
 	config.SetUsePerformanceGraph(true)
 	config.SetUseUpperPrefixHeaders(false)
 	config.SetUpdateMatchedUserAgent(false)
-	err := dd.InitManagerFromFile(
-		manager,
+	err := dd_example.InitManagerFromFile(
+		manager.ResourceManager,
 		*config,
 		"IsMobile",
 		dataFilePath)
 	if err != nil {
-		log.Fatalln(err)
+		dd_example.Fatalln(err)
 	}
 
 	// Make sure manager object will be freed after the function execution
 	defer manager.Free()
 
 	// Run the performance tests
-	return run(manager, options)
+	return run(manager.ResourceManager, options)
 }
 
 func main() {
@@ -292,7 +289,4 @@ func main() {
 	//   IsMobile Evidence Records: 14527
 	//   Processed Evidence Records: 20000
 	//   Number of CPUs: 2
-
-	// Output:
-	// Output report to file "performance_report.log".
 }
@@ -0,0 +1,55 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package main
+
+import (
+	"testing"
+
+	dd_example "github.com/51Degrees/device-detection-examples-go/v4/dd"
+	"github.com/51Degrees/device-detection-examples-go/v4/dd/internal/testutil"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+func TestMain(m *testing.M) {
+	dd_example.EnableLeakTracking(true)
+	testutil.Main(m, dd_example.ReportLeaks)
+}
+
+// Test that the example writes its report with each performance profile. The
+// options are given directly as the flags of the test binary are not those of
+// the example. The embedded fixtures are used if the Evidence Records file is
+// not available.
+func TestPerformance(t *testing.T) {
+	options := dd_example.Options{
+		DataFilePath: testutil.DataFilePathOrSkip(t),
+		EvidenceFilePath: testutil.EvidenceFilePathOrFixtures(
+			t, dd_example.FixtureEvidence()),
+		Iterations: 1,
+	}
+	for _, perf := range dd_example.ExampleProfiles(dd.InMemory) {
+		testutil.CheckOutput(t,
+			"Output report to file \"performance_report.log\".",
+			runPerformance(perf, options))
+	}
+}
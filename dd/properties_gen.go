@@ -0,0 +1,85 @@
+// Code generated by gen_properties from 51Degrees-LiteV4.1.hash. DO NOT EDIT.
+
+package dd_example
+
+// Names of the properties in the data file.
+const (
+	PropertyBrowserName        = "BrowserName"
+	PropertyBrowserVendor      = "BrowserVendor"
+	PropertyBrowserVersion     = "BrowserVersion"
+	PropertyDeviceType         = "DeviceType"
+	PropertyHardwareModel      = "HardwareModel"
+	PropertyHardwareName       = "HardwareName"
+	PropertyHardwareVendor     = "HardwareVendor"
+	PropertyIsMobile           = "IsMobile"
+	PropertyPlatformName       = "PlatformName"
+	PropertyPlatformVendor     = "PlatformVendor"
+	PropertyPlatformVersion    = "PlatformVersion"
+	PropertyScreenPixelsHeight = "ScreenPixelsHeight"
+	PropertyScreenPixelsWidth  = "ScreenPixelsWidth"
+)
+
+// BrowserName returns the value of the BrowserName property.
+func (d *DeviceResults) BrowserName() (string, error) {
+	return d.String(PropertyBrowserName)
+}
+
+// BrowserVendor returns the value of the BrowserVendor property.
+func (d *DeviceResults) BrowserVendor() (string, error) {
+	return d.String(PropertyBrowserVendor)
+}
+
+// BrowserVersion returns the value of the BrowserVersion property.
+func (d *DeviceResults) BrowserVersion() (Version, error) {
+	return d.Version(PropertyBrowserVersion)
+}
+
+// DeviceType returns the value of the DeviceType property.
+func (d *DeviceResults) DeviceType() (string, error) {
+	return d.String(PropertyDeviceType)
+}
+
+// HardwareModel returns the value of the HardwareModel property.
+func (d *DeviceResults) HardwareModel() (string, error) {
+	return d.String(PropertyHardwareModel)
+}
+
+// HardwareName returns the value of the HardwareName property.
+func (d *DeviceResults) HardwareName() ([]string, error) {
+	return d.Strings(PropertyHardwareName)
+}
+
+// HardwareVendor returns the value of the HardwareVendor property.
+func (d *DeviceResults) HardwareVendor() (string, error) {
+	return d.String(PropertyHardwareVendor)
+}
+
+// IsMobile returns the value of the IsMobile property.
+func (d *DeviceResults) IsMobile() (bool, error) {
+	return d.Bool(PropertyIsMobile)
+}
+
+// PlatformName returns the value of the PlatformName property.
+func (d *DeviceResults) PlatformName() (string, error) {
+	return d.String(PropertyPlatformName)
+}
+
+// PlatformVendor returns the value of the PlatformVendor property.
+func (d *DeviceResults) PlatformVendor() (string, error) {
+	return d.String(PropertyPlatformVendor)
+}
+
+// PlatformVersion returns the value of the PlatformVersion property.
+func (d *DeviceResults) PlatformVersion() (Version, error) {
+	return d.Version(PropertyPlatformVersion)
+}
+
+// ScreenPixelsHeight returns the value of the ScreenPixelsHeight property.
+func (d *DeviceResults) ScreenPixelsHeight() (int, error) {
+	return d.Int(PropertyScreenPixelsHeight)
+}
+
+// ScreenPixelsWidth returns the value of the ScreenPixelsWidth property.
+func (d *DeviceResults) ScreenPixelsWidth() (int, error) {
+	return d.Int(PropertyScreenPixelsWidth)
+}
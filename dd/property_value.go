@@ -0,0 +1,147 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package dd_example
+
+import (
+	"strings"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+// ValueReason is why a property value returned by GetPropertyValue does or
+// does not have values.
+type ValueReason int
+
+const (
+	// The property has values for the evidence
+	ValueFound ValueReason = iota
+	// The property is not in the data file or was not required when the
+	// resource manager was initialised
+	ValueNotLoaded
+	// The evidence was not enough to determine a value
+	ValueNoValue
+	// The evidence matched a profile which is null for the property's
+	// component, e.g. the hardware of a crawler
+	ValueNull
+	// The engine returned an error when getting the value
+	ValueError
+)
+
+// Start of the message the engine returns when the matched profile is null
+const nullProfileMessage = "No matching profiles could be found"
+
+// String returns the reason in a form which can be displayed to a user.
+func (r ValueReason) String() string {
+	switch r {
+	case ValueFound:
+		return "Found"
+	case ValueNotLoaded:
+		return "Not loaded"
+	case ValueNoValue:
+		return "No value"
+	case ValueNull:
+		return "Null"
+	case ValueError:
+		return "Error"
+	}
+	return "Unknown reason"
+}
+
+// PropertyValue is the result of getting a property from the results of a
+// detection. Values is only set when Reason is ValueFound. Otherwise Message
+// explains why, using the engine's message or the error.
+type PropertyValue struct {
+	Property string
+	Values   []string
+	Reason   ValueReason
+	Message  string
+	Err      error
+}
+
+// HasValue returns true if the property has values for the evidence.
+func (v PropertyValue) HasValue() bool {
+	return v.Reason == ValueFound
+}
+
+// Value returns the values of the property separated by commas.
+func (v PropertyValue) Value() string {
+	return strings.Join(v.Values, ",")
+}
+
+// ValueOr returns the values of the property separated by commas, or the
+// fallback if the property does not have values.
+func (v PropertyValue) ValueOr(fallback string) string {
+	if !v.HasValue() {
+		return fallback
+	}
+	return v.Value()
+}
+
+// String returns the values of the property separated by commas, or the
+// reason it does not have values. This is how a value is rendered by a
+// template.
+func (v PropertyValue) String() string {
+	return v.ValueOr(v.Reason.String())
+}
+
+// GetPropertyValue returns the values of a property from the results of a
+// detection, or the reason it does not have any.
+func GetPropertyValue(results *dd.ResultsHash, property string) PropertyValue {
+	v := PropertyValue{Property: property}
+	index := results.RequiredPropertyIndexFromName(property)
+	if index < 0 {
+		v.Reason = ValueNotLoaded
+		v.Message = "The property is not in the data file or was not " +
+			"required when the resource manager was initialised."
+		return v
+	}
+	hasValues, err := results.HasValuesByIndex(index)
+	if err != nil {
+		return errorValue(v, err)
+	}
+	if !hasValues {
+		v.Message, err = results.NoValueReasonMessageByIndex(index)
+		if err != nil {
+			return errorValue(v, err)
+		}
+		v.Reason = ValueNoValue
+		if strings.HasPrefix(v.Message, nullProfileMessage) {
+			v.Reason = ValueNull
+		}
+		return v
+	}
+	value, err := results.ValuesString(property, valuesSeparator)
+	if err != nil {
+		return errorValue(v, err)
+	}
+	v.Values = strings.Split(value, valuesSeparator)
+	return v
+}
+
+// errorValue returns the property value with the reason set to the error.
+func errorValue(v PropertyValue, err error) PropertyValue {
+	v.Reason = ValueError
+	v.Message = err.Error()
+	v.Err = err
+	return v
+}
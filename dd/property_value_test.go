@@ -0,0 +1,76 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package dd_example
+
+import (
+	"testing"
+
+	"github.com/51Degrees/device-detection-examples-go/v4/dd/internal/testutil"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+// Test that property values report why they do or do not have values.
+func TestGetPropertyValue(t *testing.T) {
+	filePath := testutil.DataFilePathOrSkip(t)
+	manager := dd.NewResourceManager()
+	config := dd.NewConfigHash(dd.Balanced)
+	if err := dd.InitManagerFromFile(manager, *config, "", filePath); err != nil {
+		t.Fatalf("Failed to initialize resource manager. %v", err)
+	}
+	defer manager.Free()
+
+	results := dd.NewResultsHash(manager, 1, 0)
+	defer results.Free()
+
+	testData := []struct {
+		ua       string
+		property string
+		expected ValueReason
+	}{
+		{iPhoneUA, PropertyIsMobile, ValueFound},
+		{iPhoneUA, "NoSuchProperty", ValueNotLoaded},
+		{"", PropertyBrowserName, ValueNoValue},
+	}
+	for _, data := range testData {
+		if err := results.MatchUserAgent(data.ua); err != nil {
+			t.Fatalf("Failed to perform detection. %v", err)
+		}
+		v := GetPropertyValue(results, data.property)
+		if v.Reason != data.expected {
+			t.Errorf("Expected '%s' for '%s' to be '%s', but got '%s': %s",
+				data.property, data.ua, data.expected, v.Reason, v.Message)
+		}
+		if v.HasValue() != (len(v.Values) > 0) {
+			t.Errorf("Expected '%s' to have values only when found", data.property)
+		}
+		if !v.HasValue() && v.String() != v.Reason.String() {
+			t.Errorf("Expected '%s' to render as '%s', but got '%s'",
+				data.property, v.Reason, v.String())
+		}
+	}
+}
+
+const iPhoneUA = "Mozilla/5.0 (iPhone; CPU iPhone OS 7_1 like Mac OS X) " +
+	"AppleWebKit/537.51.2 (KHTML, like Gecko) Version/7.0 Mobile/11D167 " +
+	"Safari/9537.53"
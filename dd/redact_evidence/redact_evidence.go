@@ -0,0 +1,442 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package main
+
+/*
+This example illustrates how Evidence Records captured from real traffic can be
+redacted before they are shared or committed, and how to prove that the
+redaction does not change detection results.
+
+Each record of the input Evidence Records file is written to the output file
+with:
+  - keys which are not used by the engine removed, including cookies and
+    unknown query parameters. The count of a deduplicated record is kept so
+    that it is still weighted by the number of times it occurred,
+  - IP addresses in the retained keys either truncated to their network
+    (/24 for IPv4, /48 for IPv6) or replaced by a salted hash mapped into a
+    private address range. Hashing requires a secret salt, as without one
+    the hash of every IPv4 address can easily be computed.
+
+A report of what was removed is printed at the end. With the `-verify` option
+each record is detected both before and after redaction and the program exits
+with a non-zero code if any property value differs.
+
+To run this example, perform the following command:
+```
+go run redact_evidence.go -e capture.yml -o capture.redacted.yml -verify
+```
+
+Additional keys can be retained with `-keep`, e.g.
+`-keep "header.x-forwarded-for"`, in which case any IP addresses they carry
+are redacted.
+*/
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	dd_example "github.com/51Degrees/device-detection-examples-go/v4/dd"
+	"gopkg.in/yaml.v3"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+// Maximum number of verification differences to print
+const maxDifferencesShown = 10
+
+// Keys which carry IP addresses
+var ipKeys = map[string]bool{
+	"server.client-ip":       true,
+	"header.x-forwarded-for": true,
+	"header.x-real-ip":       true,
+	"header.forwarded":       true,
+	"header.true-client-ip":  true,
+}
+
+type options struct {
+	DataFilePath     string
+	EvidenceFilePath string
+	OutputFilePath   string
+	IPMode           string
+	Salt             string
+	Keep             string
+	Verify           bool
+	showHelp         bool
+}
+
+func parseOptions() options {
+	o := options{}
+
+	flag.StringVar(&o.DataFilePath, "data-file", "../"+dd_example.LiteDataFile, "Path to a 51Degrees Hash data file")
+	flag.StringVar(&o.DataFilePath, "d", o.DataFilePath, "Alias for -data-file")
+
+	flag.StringVar(&o.EvidenceFilePath, "evidence-file", "../"+dd_example.EvidenceFileYaml, "Path to a Evidence Records YAML file to redact")
+	flag.StringVar(&o.EvidenceFilePath, "e", o.EvidenceFilePath, "Alias for -evidence-file")
+
+	flag.StringVar(&o.OutputFilePath, "output", "", "Path to the redacted output file. Defaults to [evidence file].redacted.yml")
+	flag.StringVar(&o.OutputFilePath, "o", o.OutputFilePath, "Alias for -output")
+
+	flag.StringVar(&o.IPMode, "ip-mode", "truncate", "How IP addresses are redacted: 'truncate' or 'hash'")
+	flag.StringVar(&o.Salt, "salt", "", "Secret salt used to hash IP addresses. Required by the 'hash' IP mode")
+	flag.StringVar(&o.Keep, "keep", "", "Comma separated list of additional 'prefix.key' evidence keys to retain")
+
+	flag.BoolVar(&o.Verify, "verify", false, "Verify that detection results are unchanged by redaction")
+
+	flag.BoolVar(&o.showHelp, "help", false, "Print help")
+	flag.BoolVar(&o.showHelp, "h", o.showHelp, "Alias for -help")
+
+	flag.Parse()
+	return o
+}
+
+// redactor removes and rewrites evidence which should not be shared.
+type redactor struct {
+	// Lower cased 'prefix.key' evidence keys to retain
+	keep   map[string]bool
+	ipMode string
+	salt   []byte
+}
+
+// report counts what has been removed or rewritten by a redactor.
+type report struct {
+	records     uint64
+	removedKeys map[string]uint64
+	redactedIPs uint64
+}
+
+// newRedactor creates a redactor retaining the evidence keys required by the
+// engine and any additional keys given.
+func newRedactor(
+	keys []dd.EvidenceKey,
+	extra []string,
+	ipMode string,
+	salt string) (*redactor, error) {
+	if ipMode != "truncate" && ipMode != "hash" {
+		return nil, fmt.Errorf("unknown IP mode '%s'", ipMode)
+	}
+	if ipMode == "hash" && salt == "" {
+		return nil, fmt.Errorf("the 'hash' IP mode requires a secret salt")
+	}
+	keep := make(map[string]bool)
+	for _, k := range keys {
+		switch k.Prefix {
+		case dd.HttpHeaderString:
+			keep["header."+strings.ToLower(k.Key)] = true
+		case dd.HttpEvidenceQuery:
+			keep["query."+strings.ToLower(k.Key)] = true
+		case dd.HttpEvidenceCookie:
+			keep["cookie."+strings.ToLower(k.Key)] = true
+		case dd.HttpEvidenceServer:
+			keep["server."+strings.ToLower(k.Key)] = true
+		}
+	}
+	for _, k := range extra {
+		if k = strings.TrimSpace(k); k != "" {
+			keep[strings.ToLower(k)] = true
+		}
+	}
+	// Needed to weight deduplicated records
+	keep[dd_example.CountKey] = true
+	return &redactor{keep, ipMode, []byte(salt)}, nil
+}
+
+// redactIP truncates or hashes a single IP address. Values which are not IP
+// addresses are removed entirely as they may still identify the client.
+func (r *redactor) redactIP(value string) string {
+	ip := net.ParseIP(strings.TrimSpace(value))
+	if ip == nil {
+		return ""
+	}
+	v4 := ip.To4()
+	if r.ipMode == "truncate" {
+		if v4 != nil {
+			return v4.Mask(net.CIDRMask(24, 32)).String()
+		}
+		return ip.Mask(net.CIDRMask(48, 128)).String()
+	}
+
+	// Map the salted hash into a private range so the value is still a
+	// valid address of the same family.
+	mac := hmac.New(sha256.New, r.salt)
+	mac.Write(ip)
+	sum := mac.Sum(nil)
+	if v4 != nil {
+		return net.IPv4(10, sum[0], sum[1], sum[2]).String()
+	}
+	hashed := make(net.IP, net.IPv6len)
+	hashed[0] = 0xfd
+	copy(hashed[1:], sum)
+	return hashed.String()
+}
+
+// redactIPs redacts each address in a comma separated list of addresses.
+func (r *redactor) redactIPs(value string) string {
+	parts := strings.Split(value, ",")
+	redacted := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if ip := r.redactIP(p); ip != "" {
+			redacted = append(redacted, ip)
+		}
+	}
+	return strings.Join(redacted, ", ")
+}
+
+// redact returns a copy of an Evidence Record with evidence which should not
+// be shared removed or rewritten, updating the report.
+func (r *redactor) redact(record map[string]string, rep *report) map[string]string {
+	rep.records++
+	redacted := make(map[string]string, len(record))
+	for k, v := range record {
+		lowerKey := strings.ToLower(k)
+		if !r.keep[lowerKey] {
+			rep.removedKeys[lowerKey]++
+			continue
+		}
+		if ipKeys[lowerKey] {
+			if lowerKey == "header.forwarded" {
+				// The structure of the Forwarded header cannot be kept
+				// without exposing the proxies, so it is dropped.
+				rep.removedKeys[lowerKey]++
+				continue
+			}
+			v = r.redactIPs(v)
+			if v == "" {
+				// None of the values were IP addresses
+				rep.removedKeys[lowerKey]++
+				continue
+			}
+			rep.redactedIPs++
+		}
+		redacted[k] = v
+	}
+	return redacted
+}
+
+// print writes the report to w.
+func (rep *report) print(w io.Writer) {
+	fmt.Fprintf(w, "Records redacted: %d\n", rep.records)
+	fmt.Fprintf(w, "IP address values redacted: %d\n", rep.redactedIPs)
+	keys := make([]string, 0, len(rep.removedKeys))
+	for k := range rep.removedKeys {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fmt.Fprintf(w, "Keys removed: %d\n", len(keys))
+	for _, k := range keys {
+		reason := "not used by the engine"
+		switch {
+		case strings.HasPrefix(k, "query."):
+			reason = "unknown query parameter"
+		case strings.HasPrefix(k, "cookie."):
+			reason = "cookie not used by the engine"
+		case ipKeys[k]:
+			reason = "IP address"
+		}
+		fmt.Fprintf(w, "\t%s: %d (%s)\n", k, rep.removedKeys[k], reason)
+	}
+}
+
+// detect performs a detection on an Evidence Record and returns the values of
+// all available properties and the device id.
+func detect(
+	manager *dd.ResourceManager,
+	record map[string]string) map[string]string {
+	evidence := dd_example.ExtractEvidence(dd_example.ConvertEvidenceMap(record))
+	defer evidence.Free()
+	results := dd_example.NewTrackedResults(manager, uint32(evidence.Count()), 0)
+	defer results.Free()
+
+	err := results.MatchEvidence(evidence.Evidence)
+	if err != nil {
+		dd_example.Fatalf("ERROR: Failed to perform detection. %v\n", err)
+	}
+	values := make(map[string]string)
+	for i, property := range results.AvailableProperties() {
+		hasValues, err := results.HasValuesByIndex(i)
+		if err != nil {
+			dd_example.Fatalln(err)
+		}
+		if !hasValues {
+			continue
+		}
+		value, err := results.ValuesString(property, ",")
+		if err != nil {
+			dd_example.Fatalln(err)
+		}
+		values[property] = value
+	}
+	values["DeviceId"], err = results.DeviceId()
+	if err != nil {
+		dd_example.Fatalf("ERROR: Failed to get unique DeviceID: %v", err)
+	}
+	return values
+}
+
+// differences returns a description of each property value which differs
+// between two sets of results.
+func differences(original, redacted map[string]string) []string {
+	var diffs []string
+	for property, value := range original {
+		if redacted[property] != value {
+			diffs = append(diffs, fmt.Sprintf(
+				"%s: '%s' became '%s'", property, value, redacted[property]))
+		}
+	}
+	for property, value := range redacted {
+		if _, ok := original[property]; !ok {
+			diffs = append(diffs, fmt.Sprintf(
+				"%s: no value became '%s'", property, value))
+		}
+	}
+	sort.Strings(diffs)
+	return diffs
+}
+
+// redactFile redacts each record of the evidence file to the output file.
+// When verifying, returns the number of records whose detection results
+// were changed by the redaction.
+func redactFile(
+	manager *dd.ResourceManager,
+	r *redactor,
+	o options,
+	rep *report) uint64 {
+	in, err := os.Open(o.EvidenceFilePath)
+	if err != nil {
+		dd_example.Fatalf("ERROR: Failed to open file \"%s\".\n", o.EvidenceFilePath)
+	}
+	defer in.Close()
+
+	out, err := os.Create(o.OutputFilePath)
+	if err != nil {
+		dd_example.Fatalf("ERROR: Failed to create file \"%s\".\n", o.OutputFilePath)
+	}
+	defer func() {
+		if err := out.Close(); err != nil {
+			dd_example.Fatalf("ERROR: Failed to close file \"%s\".\n", o.OutputFilePath)
+		}
+	}()
+
+	var changed uint64
+	enc := dd_example.NewEvidenceWriter(out)
+	dec := yaml.NewDecoder(in)
+	for i := 0; ; i++ {
+		// Decode Evidence file by line
+		var doc map[string]string
+		if err := dec.Decode(&doc); err == io.EOF {
+			break
+		} else if err != nil {
+			dd_example.Fatalf("ERROR: Failed during decoding file \"%s\". %v\n", o.EvidenceFilePath, err)
+		}
+
+		redacted := r.redact(doc, rep)
+		if _, err := enc.Write(redacted); err != nil {
+			dd_example.Fatalf("ERROR: Failed during encoding file \"%s\". %v\n", o.OutputFilePath, err)
+		}
+
+		if o.Verify {
+			diffs := differences(detect(manager, doc), detect(manager, redacted))
+			if len(diffs) > 0 {
+				if changed < maxDifferencesShown {
+					log.Printf("Record %d changed by redaction:\n\t%s\n",
+						i, strings.Join(diffs, "\n\t"))
+				}
+				changed++
+			}
+		}
+	}
+	if rep.records > 0 {
+		if err := enc.Close(); err != nil {
+			dd_example.Fatalf("ERROR: Failed to write end for file \"%s\". %v\n", o.OutputFilePath, err)
+		}
+	}
+	return changed
+}
+
+func main() {
+	defer dd_example.ReportLeaksAtExit()
+
+	o := parseOptions()
+	if o.showHelp {
+		flag.Usage()
+		return
+	}
+	dataFilePath := dd_example.GetFilePathByPath(o.DataFilePath)
+	o.EvidenceFilePath = dd_example.GetFilePathByPath(o.EvidenceFilePath)
+	if o.OutputFilePath == "" {
+		ext := filepath.Ext(o.EvidenceFilePath)
+		o.OutputFilePath = strings.TrimSuffix(o.EvidenceFilePath, ext) + ".redacted.yml"
+	}
+
+	// Initialise manager with all properties so verification compares
+	// every available value.
+	manager := dd_example.NewTrackedManager()
+	config := dd.NewConfigHash(dd.Balanced)
+	err := dd.InitManagerFromFile(
+		manager.ResourceManager,
+		*config,
+		"",
+		dataFilePath)
+	if err != nil {
+		dd_example.Fatalln(err)
+	}
+
+	// Make sure manager object will be freed after the function execution
+	defer manager.Free()
+
+	r, err := newRedactor(
+		manager.HttpHeaderKeys,
+		strings.Split(o.Keep, ","),
+		o.IPMode,
+		o.Salt)
+	if err != nil {
+		dd_example.Fatalf("ERROR: %v\n", err)
+	}
+
+	rep := report{removedKeys: make(map[string]uint64)}
+	changed := redactFile(manager.ResourceManager, r, o, &rep)
+	rep.print(os.Stdout)
+	fmt.Printf("Output to \"%s\".\n", o.OutputFilePath)
+
+	if o.Verify {
+		if changed > 0 {
+			// Free explicitly as deferred calls do not run on exit
+			manager.Free()
+			dd_example.ReportLeaksAtExit()
+			fmt.Printf("Verification failed: %d of %d records have "+
+				"different detection results after redaction.\n",
+				changed, rep.records)
+			os.Exit(1)
+		}
+		fmt.Printf("Verification passed: detection results of all %d "+
+			"records are unchanged by redaction.\n", rep.records)
+	}
+}
@@ -0,0 +1,126 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+// Test that keys not used by the engine are removed and IP addresses in the
+// retained keys are redacted.
+func TestRedact(t *testing.T) {
+	keys := []dd.EvidenceKey{
+		{Prefix: dd.HttpHeaderString, Key: "User-Agent"},
+		{Prefix: dd.HttpEvidenceQuery, Key: "User-Agent"},
+	}
+	record := map[string]string{
+		"header.user-agent":      "Mozilla/5.0 (Test)",
+		"query.User-Agent":       "Mozilla/5.0 (Query)",
+		"query.email":            "someone@example.com",
+		"cookie.session":         "abc123",
+		"header.accept-language": "en-GB",
+		"server.client-ip":       "203.0.113.77",
+		"header.x-forwarded-for": "203.0.113.77, 2001:db8:cafe:1::17, unknown",
+		"header.forwarded":       "for=203.0.113.77",
+		"header.x-real-ip":       "unknown",
+		"meta.count":             "3",
+	}
+	extra := []string{
+		"server.client-ip",
+		"header.X-Forwarded-For",
+		"header.forwarded",
+		"header.x-real-ip",
+	}
+
+	testData := []struct {
+		ipMode   string
+		expected map[string]string
+	}{
+		{
+			"truncate",
+			map[string]string{
+				"header.user-agent":      "Mozilla/5.0 (Test)",
+				"query.User-Agent":       "Mozilla/5.0 (Query)",
+				"server.client-ip":       "203.0.113.0",
+				"header.x-forwarded-for": "203.0.113.0, 2001:db8:cafe::",
+				"meta.count":             "3",
+			},
+		},
+		{
+			"hash",
+			nil,
+		},
+	}
+
+	for _, data := range testData {
+		r, err := newRedactor(keys, extra, data.ipMode, "salt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rep := report{removedKeys: make(map[string]uint64)}
+		redacted := r.redact(record, &rep)
+
+		if data.expected != nil && !reflect.DeepEqual(redacted, data.expected) {
+			t.Errorf("Expected '%v', but got '%v'", data.expected, redacted)
+		}
+		for _, k := range []string{
+			"query.email",
+			"cookie.session",
+			"header.accept-language",
+			"header.forwarded",
+			"header.x-real-ip"} {
+			if _, ok := redacted[k]; ok {
+				t.Errorf("Expected '%s' to be removed", k)
+			}
+			if rep.removedKeys[k] != 1 {
+				t.Errorf("Expected '%s' to be reported as removed", k)
+			}
+		}
+		if ip := redacted["server.client-ip"]; ip == "203.0.113.77" || ip == "" {
+			t.Errorf("Expected client IP to be redacted, but got '%s'", ip)
+		}
+		if rep.redactedIPs != 2 {
+			t.Errorf("Expected '2' IP values redacted, but got '%d'",
+				rep.redactedIPs)
+		}
+	}
+
+	// Hashing is deterministic for the same salt and address family
+	r, _ := newRedactor(keys, nil, "hash", "salt")
+	if a, b := r.redactIP("192.0.2.1"), r.redactIP("192.0.2.1"); a != b {
+		t.Errorf("Expected the same hash, but got '%s' and '%s'", a, b)
+	}
+	if ip := r.redactIP("192.0.2.1"); ip[:3] != "10." {
+		t.Errorf("Expected an address in 10.0.0.0/8, but got '%s'", ip)
+	}
+
+	if _, err := newRedactor(keys, nil, "remove", ""); err == nil {
+		t.Error("Expected an error for an unknown IP mode")
+	}
+	if _, err := newRedactor(keys, nil, "hash", ""); err == nil {
+		t.Error("Expected an error for hashing without a salt")
+	}
+}
@@ -72,20 +72,20 @@ func generateHash(str string) uint32 {
 func executeTest(
 	wg *sync.WaitGroup,
 	manager *dd.ResourceManager,
-	evidence *dd.Evidence,
+	evidence *dd_example.TrackedEvidence,
 	rep *freport,
 	iteration uint32) {
 	defer evidence.Free()
 	// Create results
-	results := dd.NewResultsHash(manager, uint32(evidence.Count()), 0)
+	results := dd_example.NewTrackedResults(manager, uint32(evidence.Count()), 0)
 
 	// Make sure results object is freed after function execution.
 	defer results.Free()
 
 	// Perform detection
-	err := results.MatchEvidence(evidence)
+	err := results.MatchEvidence(evidence.Evidence)
 	if err != nil {
-		log.Fatal("ERROR: Failed to perform detection.")
+		dd_example.Fatal("ERROR: Failed to perform detection.")
 	}
 
 	// Loop through all properties
@@ -95,7 +95,7 @@ func executeTest(
 			property,
 			",")
 		if err != nil {
-			log.Fatalln(err)
+			dd_example.Fatalln(err)
 		}
 		rep.updateHashCode(generateHash(value), iteration)
 	}
@@ -121,12 +121,12 @@ func performDetectionIterations(
 		// Loop through the Evidence file
 		file, err := os.OpenFile(evidenceFilePath, os.O_RDONLY, 0444)
 		if err != nil {
-			log.Fatalf("ERROR: Failed to open file \"%s\".\n", evidenceFilePath)
+			dd_example.Fatalf("ERROR: Failed to open file \"%s\".\n", evidenceFilePath)
 		}
 		defer func() {
 			// Make sure the file is closed properly
 			if err := file.Close(); err != nil {
-				log.Fatalf("ERROR: Failed to close file \"%s\".\n", evidenceFilePath)
+				dd_example.Fatalf("ERROR: Failed to close file \"%s\".\n", evidenceFilePath)
 			}
 		}()
 
@@ -139,7 +139,7 @@ func performDetectionIterations(
 				break
 			} else if err != nil {
 				// Make sure there is no decoder error
-				log.Fatalf("ERROR: Error during decoding file \"%s\". %v\n", evidenceFilePath, err)
+				dd_example.Fatalf("ERROR: Error during decoding file \"%s\". %v\n", evidenceFilePath, err)
 			}
 			// Increase wait group
 			wg.Add(1)
@@ -200,7 +200,7 @@ func runReloadFromFileSub(
 		if i == 0 {
 			initHashCode = rep.hashCodes[i]
 		} else if initHashCode != rep.hashCodes[i] {
-			log.Fatalf("Hash codes do not match. Initial hash code is '%d', "+
+			dd_example.Fatalf("Hash codes do not match. Initial hash code is '%d', "+
 				"but iteration '%d' has hash code '%d'. This indicates not "+
 				"all Evidence Records have been processed correctly for each "+
 				"iteration.", initHashCode, rep.hashCodes[i], i)
@@ -215,25 +215,25 @@ func runReloadFromFile(perf dd.PerformanceProfile) string {
 	dataFilePath := dd_example.GetFilePathByName([]string{dd_example.LiteDataFile})
 	evidenceFilePath := dd_example.GetFilePathByName([]string{dd_example.EvidenceFileYaml})
 	// Create Resource Manager
-	manager := dd.NewResourceManager()
+	manager := dd_example.NewTrackedManager()
 	config := dd.NewConfigHash(dd.InMemory)
 	config.SetConcurrency(uint16(runtime.NumCPU()))
 	config.SetUseUpperPrefixHeaders(false)
 	config.SetUpdateMatchedUserAgent(false)
-	err := dd.InitManagerFromFile(
-		manager,
+	err := dd_example.InitManagerFromFile(
+		manager.ResourceManager,
 		*config,
 		"IsMobile,BrowserName,DeviceType",
 		dataFilePath)
 	if err != nil {
-		log.Fatalln(err)
+		dd_example.Fatalln(err)
 	}
 
 	// Make sure manager object will be freed after the function execution
 	defer manager.Free()
 
 	// Run the performance tests
-	report := runReloadFromFileSub(manager, evidenceFilePath)
+	report := runReloadFromFileSub(manager.ResourceManager, evidenceFilePath)
 	return report
 }
 
@@ -247,7 +247,4 @@ func main() {
 	// 2021/11/10 11:42:05 Hashcode '4217895257' for iteration '1'.
 	// 2021/11/10 11:42:05 Hashcode '4217895257' for iteration '2'.
 	// 2021/11/10 11:42:05 Hashcode '4217895257' for iteration '3'.
-
-	// Output:
-	// Program execution complete.
 }
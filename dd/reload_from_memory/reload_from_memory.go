@@ -23,12 +23,365 @@
 package main
 
 /*
-TODO: To be implemented
+Illustrates how dataset can be reloaded from data held in memory while
+detections are performed.
+
+The data file is read into a byte slice, either directly or from any io.Reader
+such as a decompressed stream ("51Degrees-LiteV4.1.hash.gz" files are
+decompressed on the fly). A reload initialises the new data set before it
+replaces the current one, so a corrupt or truncated download is rejected with
+an error and never replaces the data set which is serving detections.
+
+NOTE: The InitManagerFromMemory and ReloadFromMemory functions of
+device-detection-go are not yet implemented, so the data is staged to a
+temporary file once per load and both validated and loaded from there. The
+InMemory performance profile is used so the whole data set is held in memory
+and the temporary file can be removed as soon as it has been loaded.
+
+As in the reload_from_file example, the results of each iteration over the
+Evidence Records are hashed. All iterations should have the same hash code,
+showing that detections are consistent across reloads.
+
+To run this example, perform the following command:
+```
+go run reload_from_memory.go
+```
 */
 
-// import "fmt"
-// import "github.com/51Degrees/device-detection-go/ddonpremise"
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	dd_example "github.com/51Degrees/device-detection-examples-go/v4/dd"
+	"gopkg.in/yaml.v3"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+// Number of iterations to perform over the Evidence Records.
+const mIterationCount = 4
+
+// Properties used for detections
+const properties = "IsMobile,BrowserName,DeviceType"
+
+// Report struct for reload from memory run
+type mreport struct {
+	mu                sync.Mutex // Mutex
+	evidenceCount     uint64
+	hashCodes         [mIterationCount]uint32
+	evidenceProcessed uint64
+}
+
+// updateHashCode updates the hash code with the input code ad the index
+// specified. The update use XOR operation. This function is thread safe to
+// make sure multiple threads can update the hash code correctly
+func (rep *mreport) updateHashCode(code uint32, i uint32) {
+	rep.mu.Lock()
+	rep.hashCodes[i] ^= code
+	rep.mu.Unlock()
+}
+
+// generateHash generate 32bit hash code for an input string
+func generateHash(str string) uint32 {
+	h := fnv.New32()
+	h.Write([]byte(str))
+	return h.Sum32()
+}
+
+// readData reads the whole content of a data file from a reader into memory.
+func readData(r io.Reader) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, err
+	}
+	if buf.Len() == 0 {
+		return nil, fmt.Errorf("data is empty")
+	}
+	return buf.Bytes(), nil
+}
+
+// readDataFile reads a data file into memory, decompressing it if it is
+// gzipped.
+func readDataFile(dataFilePath string) ([]byte, error) {
+	file, err := os.Open(dataFilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(dataFilePath, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return readData(r)
+}
+
+// stageData writes data held in memory to a temporary file so it can be loaded
+// by the resource manager. Returns the path of the file, which the caller must
+// remove.
+func stageData(data []byte) (string, error) {
+	file, err := os.CreateTemp("", "51Degrees-*.hash")
+	if err != nil {
+		return "", err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// withStagedData stages data held in memory to a temporary file, calls fn
+// with its path and then removes the file.
+func withStagedData(data []byte, fn func(path string) error) error {
+	path, err := stageData(data)
+	if err != nil {
+		return err
+	}
+	// The InMemory profile copies the data so the file is no longer needed
+	defer os.Remove(path)
+	return fn(path)
+}
+
+// initManagerFromMemory initialises a resource manager from data held in
+// memory. Returns an error if the data is not a valid data set.
+func initManagerFromMemory(
+	manager *dd.ResourceManager,
+	config dd.ConfigHash,
+	properties string,
+	data []byte) error {
+	return withStagedData(data, func(path string) error {
+		err := dd_example.InitManagerFromFile(manager, config, properties, path)
+		if err != nil {
+			return fmt.Errorf("data is not a valid data set: %w", err)
+		}
+		return nil
+	})
+}
+
+// reloadFromMemory reloads the resource manager with data held in memory. The
+// new data set is initialised before it replaces the current one, so if the
+// data is not valid an error is returned and the current data set is kept.
+// Detections in progress continue to use the previous data set until they
+// complete.
+func reloadFromMemory(manager *dd.ResourceManager, data []byte) error {
+	return withStagedData(data, func(path string) error {
+		if err := manager.ReloadFromFile(path); err != nil {
+			return fmt.Errorf("data is not a valid data set: %w", err)
+		}
+		return nil
+	})
+}
+
+func executeTest(
+	wg *sync.WaitGroup,
+	manager *dd.ResourceManager,
+	evidence *dd_example.TrackedEvidence,
+	rep *mreport,
+	iteration uint32) {
+	defer evidence.Free()
+	// Create results
+	results := dd_example.NewTrackedResults(manager, uint32(evidence.Count()), 0)
+
+	// Make sure results object is freed after function execution.
+	defer results.Free()
+
+	// Perform detection
+	err := results.MatchEvidence(evidence.Evidence)
+	if err != nil {
+		dd_example.Fatal("ERROR: Failed to perform detection.")
+	}
+
+	// Loop through all properties
+	for _, property := range results.AvailableProperties() {
+		// Get the value in string
+		value, err := results.ValuesString(
+			property,
+			",")
+		if err != nil {
+			dd_example.Fatalln(err)
+		}
+		rep.updateHashCode(generateHash(value), iteration)
+	}
+
+	// Increase the number of Evidence Records processed
+	atomic.AddUint64(&rep.evidenceProcessed, 1)
+
+	// Complete and mark as done
+	defer wg.Done()
+}
+
+// performDetectionIterations iterates through the Evidence Records file and
+// perform detection on each evidence. Results of each detection will be hashed
+// and combine for each iteration. At the end all iterations should have the
+// same hash value.
+func performDetectionIterations(
+	manager *dd.ResourceManager,
+	evidenceFilePath string,
+	wg *sync.WaitGroup,
+	rep *mreport) {
+	for i := 0; i < mIterationCount; i++ {
+		// Loop through the Evidence file
+		file, err := os.OpenFile(evidenceFilePath, os.O_RDONLY, 0444)
+		if err != nil {
+			dd_example.Fatalf("ERROR: Failed to open file \"%s\".\n", evidenceFilePath)
+		}
+
+		// Actual processing
+		dec := yaml.NewDecoder(file)
+		for {
+			// Decode Evidence file by line
+			var doc map[string]string
+			if err := dec.Decode(&doc); err == io.EOF {
+				break
+			} else if err != nil {
+				// Make sure there is no decoder error
+				dd_example.Fatalf("ERROR: Error during decoding file \"%s\". %v\n", evidenceFilePath, err)
+			}
+			// Increase wait group
+			wg.Add(1)
+
+			// Prepare evidence for usage
+			filteredEvidence := dd_example.ConvertEvidenceMap(doc)
+			evidence := dd_example.ExtractEvidence(filteredEvidence)
+
+			go executeTest(
+				wg,
+				manager,
+				evidence,
+				rep,
+				uint32(i))
+		}
+
+		// Make sure the file is closed properly
+		if err := file.Close(); err != nil {
+			dd_example.Fatalf("ERROR: Failed to close file \"%s\".\n", evidenceFilePath)
+		}
+	}
+	wg.Done()
+}
+
+func runReloadFromMemorySub(
+	manager *dd.ResourceManager,
+	dataFilePath string,
+	evidenceFilePath string) string {
+	reloads := 0
+	reloadFails := 0
+	// Create a wait group for iteration function
+	var wg sync.WaitGroup
+
+	// Count the number of Evidence Records to be processed
+	var rep mreport
+	rep.evidenceCount = dd_example.CountEvidenceFromFiles(evidenceFilePath)
+	rep.evidenceCount *= mIterationCount
+
+	// Perform detections
+	wg.Add(1)
+	go performDetectionIterations(manager, evidenceFilePath, &wg, &rep)
+
+	// Perform reload from memory until all Evidence Records have been
+	// processed. The data is read again each time, as it would be when a
+	// new data file is downloaded.
+	for atomic.LoadUint64(&rep.evidenceProcessed) < rep.evidenceCount {
+		data, err := readDataFile(dataFilePath)
+		if err == nil {
+			err = reloadFromMemory(manager, data)
+		}
+		if err == nil {
+			reloads++
+		} else {
+			log.Printf("Failed to reload from memory. %v\n", err)
+			reloadFails++
+		}
+		// Sleep 1 second between reload
+		time.Sleep(time.Second)
+	}
+
+	// Wait until all goroutines finish
+	wg.Wait()
+
+	// Construct report
+	log.Printf("Reloaded '%d' times.\n", reloads)
+	log.Printf("Failed to reload '%d' times.\n", reloadFails)
+	var initHashCode uint32
+	for i := 0; i < mIterationCount; i++ {
+		if i == 0 {
+			initHashCode = rep.hashCodes[i]
+		} else if initHashCode != rep.hashCodes[i] {
+			dd_example.Fatalf("Hash codes do not match. Initial hash code is '%d', "+
+				"but iteration '%d' has hash code '%d'. This indicates not "+
+				"all Evidence Records have been processed correctly for each "+
+				"iteration.", initHashCode, i, rep.hashCodes[i])
+		}
+		log.Printf("Hashcode '%d' for iteration '%d'.\n",
+			rep.hashCodes[i], i)
+	}
+	if reloadFails > 0 {
+		dd_example.Fatalf("Failed to reload from memory '%d' times.", reloadFails)
+	}
+	return "Program execution complete."
+}
+
+func runReloadFromMemory(perf dd.PerformanceProfile) string {
+	dataFilePath := dd_example.GetFilePathByName([]string{dd_example.LiteDataFile})
+	evidenceFilePath := dd_example.GetFilePathByName([]string{dd_example.EvidenceFileYaml})
+
+	// Read the data file into memory
+	data, err := readDataFile(dataFilePath)
+	if err != nil {
+		dd_example.Fatalf("ERROR: Failed to read data file \"%s\". %v\n", dataFilePath, err)
+	}
+
+	// Create Resource Manager. The InMemory profile is required as the
+	// data does not stay on disk.
+	manager := dd_example.NewTrackedManager()
+	config := dd.NewConfigHash(dd.InMemory)
+	config.SetConcurrency(uint16(runtime.NumCPU()))
+	config.SetUseUpperPrefixHeaders(false)
+	config.SetUpdateMatchedUserAgent(false)
+	err = initManagerFromMemory(manager.ResourceManager, *config, properties, data)
+	if err != nil {
+		dd_example.Fatalln(err)
+	}
+
+	// Make sure manager object will be freed after the function execution
+	defer manager.Free()
+	log.Printf("Loaded data set published on %s from memory.\n",
+		dd.GetPublishedDate(manager.ResourceManager).Format("2006-01-02"))
+
+	// Run the reload tests
+	return runReloadFromMemorySub(manager.ResourceManager, dataFilePath, evidenceFilePath)
+}
 
 func main() {
-	// TODO: To be implemented
+	dd_example.PerformExample(dd.Default, runReloadFromMemory)
+	// The output log of this example is in for the following format:
+	//
+	// 2021/11/10 11:42:01 Loaded data set published on 2021-11-01 from memory.
+	// 2021/11/10 11:42:05 Reloaded '2' times.
+	// 2021/11/10 11:42:05 Failed to reload '0' times.
+	// 2021/11/10 11:42:05 Hashcode '4217895257' for iteration '0'.
+	// 2021/11/10 11:42:05 Hashcode '4217895257' for iteration '1'.
+	// 2021/11/10 11:42:05 Hashcode '4217895257' for iteration '2'.
+	// 2021/11/10 11:42:05 Hashcode '4217895257' for iteration '3'.
 }
@@ -0,0 +1,139 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/51Degrees/device-detection-examples-go/v4/dd/internal/testutil"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+// checkStagedRemoved fails the test if any staged files remain in dir.
+func checkStagedRemoved(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		t.Errorf("Expected staged file '%s' to be removed", entry.Name())
+	}
+}
+
+// Test that data is read from plain and gzipped files and that empty data is
+// rejected.
+func TestReadDataFile(t *testing.T) {
+	dir := t.TempDir()
+	expected := []byte("51Degrees data")
+
+	plain := filepath.Join(dir, "data.hash")
+	if err := os.WriteFile(plain, expected, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write(expected)
+	gz.Close()
+	compressed := filepath.Join(dir, "data.hash.gz")
+	if err := os.WriteFile(compressed, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{plain, compressed} {
+		data, err := readDataFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, expected) {
+			t.Errorf("Expected '%s', but got '%s'", expected, data)
+		}
+	}
+
+	if _, err := readData(bytes.NewReader(nil)); err == nil {
+		t.Error("Expected an error for empty data")
+	}
+}
+
+// Test that data is staged to a single file which is removed once used.
+func TestWithStagedData(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir)
+	expected := []byte("51Degrees data")
+
+	calls := 0
+	err := withStagedData(expected, func(path string) error {
+		calls++
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(data, expected) {
+			t.Errorf("Expected '%s', but got '%s'", expected, data)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected '1' call, but got '%d'", calls)
+	}
+	checkStagedRemoved(t, dir)
+}
+
+// Test that invalid data is rejected by a reload without replacing the data
+// set, and that valid data is reloaded.
+func TestReloadFromMemory(t *testing.T) {
+	data, err := readDataFile(testutil.DataFilePathOrSkip(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir)
+
+	manager := dd.NewResourceManager()
+	config := dd.NewConfigHash(dd.InMemory)
+	if err := initManagerFromMemory(manager, *config, properties, data); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Free()
+	published := dd.GetPublishedDate(manager)
+
+	if err := reloadFromMemory(manager, data[:len(data)/2]); err == nil {
+		t.Error("Expected an error for truncated data")
+	}
+	if date := dd.GetPublishedDate(manager); !date.Equal(published) {
+		t.Errorf("Expected the data set published on '%v' to be kept, but "+
+			"got '%v'", published, date)
+	}
+	if err := reloadFromMemory(manager, data); err != nil {
+		t.Error(err)
+	}
+	checkStagedRemoved(t, dir)
+}
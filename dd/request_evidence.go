@@ -0,0 +1,131 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package dd_example
+
+import (
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+// Key of the server evidence holding the IP address of the client
+const ClientIPKey = "client-ip"
+
+// QueryValue returns the first value of the query parameter with the name
+// given, ignoring case, or an empty string if there is none. An exact match is
+// preferred over one which differs only in case.
+func QueryValue(r *http.Request, name string) string {
+	if r.URL == nil {
+		return ""
+	}
+	query := r.URL.Query()
+	if v := query.Get(name); v != "" {
+		return v
+	}
+	// Names are sorted so that the same value is chosen for every request
+	names := make([]string, 0, len(query))
+	for k := range query {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		if strings.EqualFold(k, name) {
+			if v := query.Get(k); v != "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// CookieValue returns the value of the cookie with the given name, matched
+// case-insensitively. Returns an empty string if the cookie is not present.
+func CookieValue(r *http.Request, name string) string {
+	for _, c := range r.Cookies() {
+		if strings.EqualFold(c.Name, name) {
+			return c.Value
+		}
+	}
+	return ""
+}
+
+// RemoteIP returns the IP address of the remote end of the connection, or an
+// empty string if it cannot be read.
+func RemoteIP(r *http.Request) string {
+	host := r.RemoteAddr
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String()
+	}
+	return ""
+}
+
+// ExtractRequestEvidence returns the values of the required evidence keys
+// which are present in a http request, in the order of the keys. Each key is
+// looked up according to its prefix: headers, query parameters, cookies or
+// server values, of which only the client IP can be derived from the request.
+// The client IP is given by clientIP, or RemoteIP if nil.
+func ExtractRequestEvidence(
+	r *http.Request,
+	keys []dd.EvidenceKey,
+	clientIP func(r *http.Request) string) []stringEvidence {
+	if clientIP == nil {
+		clientIP = RemoteIP
+	}
+	evidence := make([]stringEvidence, 0)
+	add := func(prefix, key, value string) {
+		if value != "" {
+			evidence = append(evidence, stringEvidence{prefix, key, value})
+		}
+	}
+	for _, e := range keys {
+		switch e.Prefix {
+		case dd.HttpEvidenceQuery:
+			add("query", e.Key, QueryValue(r, e.Key))
+		case dd.HttpEvidenceCookie:
+			add("cookie", e.Key, CookieValue(r, e.Key))
+		case dd.HttpEvidenceServer, dd.HttpIpAddresses:
+			if strings.EqualFold(e.Key, ClientIPKey) {
+				add("server", e.Key, clientIP(r))
+			}
+		default:
+			add("header", e.Key, r.Header.Get(e.Key))
+		}
+	}
+	return evidence
+}
+
+// EvidenceRecord returns the evidence as an Evidence Record of 'prefix.key'
+// to value.
+func EvidenceRecord(evidence []stringEvidence) map[string]string {
+	record := make(map[string]string, len(evidence))
+	for _, e := range evidence {
+		record[e.Prefix+"."+e.Key] = e.Value
+	}
+	return record
+}
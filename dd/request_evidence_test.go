@@ -0,0 +1,97 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package dd_example
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+// Keys of each prefix for the request returned by newEvidenceRequest
+var requestKeys = []dd.EvidenceKey{
+	{Prefix: dd.HttpHeaderString, Key: "User-Agent"},
+	{Prefix: dd.HttpEvidenceQuery, Key: "Sec-CH-UA"},
+	{Prefix: dd.HttpEvidenceCookie, Key: "Session"},
+	{Prefix: dd.HttpEvidenceServer, Key: "client-ip"},
+	{Prefix: dd.HttpHeaderString, Key: "Sec-CH-UA-Mobile"},
+}
+
+// newEvidenceRequest returns a request with evidence of each prefix.
+func newEvidenceRequest() *http.Request {
+	r := httptest.NewRequest("GET", "/?sec-ch-ua=Chromium", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	r.Header.Set("User-Agent", "Mozilla/5.0 (Test)")
+	r.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+	return r
+}
+
+// Test that evidence of every prefix is extracted in the order of the keys,
+// with query parameters and cookies matched ignoring case.
+func TestExtractRequestEvidence(t *testing.T) {
+	expected := []stringEvidence{
+		{"header", "User-Agent", "Mozilla/5.0 (Test)"},
+		{"query", "Sec-CH-UA", "Chromium"},
+		{"cookie", "Session", "abc"},
+		{"server", "client-ip", "192.0.2.1"},
+	}
+	evidence := ExtractRequestEvidence(newEvidenceRequest(), requestKeys, nil)
+	if !reflect.DeepEqual(evidence, expected) {
+		t.Errorf("Expected %v, but got %v", expected, evidence)
+	}
+
+	proxied := func(r *http.Request) string { return "203.0.113.7" }
+	evidence = ExtractRequestEvidence(newEvidenceRequest(), requestKeys, proxied)
+	if ip := evidence[len(evidence)-1]; ip.Value != "203.0.113.7" {
+		t.Errorf("Expected the client IP given, but got %v", ip)
+	}
+}
+
+// Test that a captured request is the record of its extracted evidence.
+func TestCaptureRequest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.yml")
+	c, err := NewEvidenceCapture(CaptureOptions{FilePath: path, SampleRate: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CaptureRequest(newEvidenceRequest(), requestKeys, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []map[string]string{{
+		"header.user-agent": "Mozilla/5.0 (Test)",
+		"query.sec-ch-ua":   "Chromium",
+		"cookie.session":    "abc",
+		"server.client-ip":  "192.0.2.1",
+	}}
+	if records := readEvidenceRecords(t, path); !reflect.DeepEqual(records, expected) {
+		t.Errorf("Expected %v, but got %v", expected, records)
+	}
+}
@@ -0,0 +1,149 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package main
+
+/*
+This example illustrates how to see which parts of a User-Agent were matched
+by a detection, to debug why an unusual User-Agent gets a poor match.
+
+The User-Agent is printed with matched characters in green and unmatched
+characters in red, followed by the share of characters matched and the match
+metrics. With `-no-color` the matched characters are marked with '^' on the
+line below instead.
+
+To run this example, perform the following command:
+```
+go run show_match.go "Mozilla/5.0 (Windows NT 6.3; WOW64; rv:41.0) Gecko/20100101 Firefox/41.0"
+```
+If no User-Agent is given, one is read from each line of standard input.
+*/
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	dd_example "github.com/51Degrees/device-detection-examples-go/v4/dd"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+type options struct {
+	DataFilePath string
+	NoColor      bool
+	showHelp     bool
+}
+
+func parseOptions() options {
+	o := options{}
+
+	flag.StringVar(&o.DataFilePath, "data-file", "../"+dd_example.LiteDataFile, "Path to a 51Degrees Hash data file")
+	flag.StringVar(&o.DataFilePath, "d", o.DataFilePath, "Alias for -data-file")
+
+	flag.BoolVar(&o.NoColor, "no-color", false, "Mark matched characters with '^' instead of colours")
+
+	flag.BoolVar(&o.showHelp, "help", false, "Print help")
+	flag.BoolVar(&o.showHelp, "h", o.showHelp, "Alias for -help")
+
+	flag.Parse()
+	return o
+}
+
+// showMatch performs a detection on a User-Agent and prints which parts of it
+// were matched.
+func showMatch(
+	w io.Writer,
+	results *dd.ResultsHash,
+	ua string,
+	noColor bool) {
+	if err := results.MatchUserAgent(ua); err != nil {
+		dd_example.Fatalf("ERROR: Failed to perform detection on User-Agent \"%s\".\n", ua)
+	}
+	// We only use one User-Agent so there can only be one result
+	matched, err := results.UserAgent(0)
+	if err != nil {
+		dd_example.Fatalln(err)
+	}
+
+	segments := dd_example.AlignMatchedUserAgent(ua, matched)
+	if noColor {
+		fmt.Fprintf(w, "User-Agent: %s\n", ua)
+		fmt.Fprintf(w, "            %s\n", dd_example.RenderMatchMarkers(segments))
+	} else {
+		fmt.Fprintf(w, "User-Agent: %s\n", dd_example.RenderMatchANSI(segments))
+	}
+	fmt.Fprintf(w, "\tMatched: %.1f%%\n", dd_example.MatchedShare(segments)*100)
+	fmt.Fprintf(w, "\tMethod: %s\n", dd_example.MatchMethodName(results.Method()))
+	fmt.Fprintf(w, "\tDrift: %d\n", results.Drift())
+	fmt.Fprintf(w, "\tDifference: %d\n", results.Difference())
+	fmt.Fprintf(w, "\tIterations: %d\n", results.Iterations())
+	fmt.Fprintf(w, "\tSub Strings: %s\n", matched)
+}
+
+func main() {
+	defer dd_example.ReportLeaksAtExit()
+
+	o := parseOptions()
+	if o.showHelp {
+		flag.Usage()
+		return
+	}
+
+	// Initialise manager
+	manager := dd_example.NewTrackedManager()
+	config := dd.NewConfigHash(dd.Balanced)
+	// Record the matched substrings of the User-Agent in the results
+	config.SetUpdateMatchedUserAgent(true)
+	filePath := dd_example.GetFilePathByPath(o.DataFilePath)
+	err := dd.InitManagerFromFile(manager.ResourceManager, *config, "", filePath)
+	if err != nil {
+		dd_example.Fatalln(err)
+	}
+
+	// Make sure manager object will be freed after the function execution
+	defer manager.Free()
+
+	// Create results
+	results := dd_example.NewTrackedResults(manager.ResourceManager, 1, 0)
+
+	// Make sure results object is freed after function execution
+	defer results.Free()
+
+	if flag.NArg() > 0 {
+		for _, ua := range flag.Args() {
+			showMatch(os.Stdout, results.ResultsHash, ua, o.NoColor)
+		}
+		return
+	}
+	s := bufio.NewScanner(os.Stdin)
+	for s.Scan() {
+		if s.Text() != "" {
+			showMatch(os.Stdout, results.ResultsHash, s.Text(), o.NoColor)
+		}
+	}
+	if err := s.Err(); err != nil {
+		dd_example.Fatalf("ERROR: Failed to read standard input. %v\n", err)
+	}
+}
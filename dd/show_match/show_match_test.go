@@ -0,0 +1,74 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/51Degrees/device-detection-examples-go/v4/dd/internal/testutil"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+// Test that the markers under a User-Agent are aligned with its characters,
+// including User-Agents with characters of more than one byte.
+func TestShowMatch(t *testing.T) {
+	manager := dd.NewResourceManager()
+	config := dd.NewConfigHash(dd.Balanced)
+	config.SetUpdateMatchedUserAgent(true)
+	err := dd.InitManagerFromFile(
+		manager, *config, "", testutil.DataFilePathOrSkip(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Free()
+	results := dd.NewResultsHash(manager, 1, 0)
+	defer results.Free()
+
+	for _, ua := range []string{
+		"Mozilla/5.0 (Windows NT 6.3; WOW64; rv:41.0) Gecko/20100101 Firefox/41.0",
+		"Mozilla/5.0 (Linux; Android 13; Ünïcödé Phöné) AppleWebKit/537.36 " +
+			"(KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36",
+	} {
+		var out bytes.Buffer
+		showMatch(&out, results, ua, true)
+		lines := strings.Split(out.String(), "\n")
+		if lines[0] != "User-Agent: "+ua {
+			t.Fatalf("Expected the User-Agent, but got '%s'", lines[0])
+		}
+		markers := strings.TrimPrefix(lines[1], strings.Repeat(" ", 12))
+		if !strings.Contains(markers, "^") {
+			t.Errorf("Expected matched characters in '%s'", ua)
+		}
+		if utf8.RuneCountInString(markers) > utf8.RuneCountInString(ua) {
+			t.Errorf("Expected at most %d markers, but got %d",
+				utf8.RuneCountInString(ua), utf8.RuneCountInString(markers))
+		}
+		if !strings.Contains(out.String(), "\tMatched: ") {
+			t.Errorf("Expected the share matched in:\n%s", out.String())
+		}
+	}
+}
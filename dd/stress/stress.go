@@ -0,0 +1,352 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package stress
+
+/*
+This package contains a stress harness which checks that detections stay
+consistent while a resource manager or an on-premise engine is reloaded. It is
+kept apart from the dd package so that the examples do not depend on the
+on-premise engine.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	dd_example "github.com/51Degrees/device-detection-examples-go/v4/dd"
+	"github.com/51Degrees/device-detection-examples-go/v4/onpremise/common"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+	"github.com/51Degrees/device-detection-go/v4/onpremise"
+)
+
+// Target performs detections which are checked by Run while it is
+// reloaded. Detect returns a fingerprint of the values detected for an
+// Evidence Records entry, which must not change when the same data file is
+// reloaded.
+type Target interface {
+	Detect(record map[string]string) (string, error)
+	Reload() error
+}
+
+// ResultFingerprint returns the values of the properties, or of all available
+// properties if none are given, and the device ID as a single string.
+func ResultFingerprint(results *dd.ResultsHash, properties []string) (string, error) {
+	if len(properties) == 0 {
+		properties = results.AvailableProperties()
+	}
+	var b strings.Builder
+	for _, property := range properties {
+		value, err := results.ValuesString(property, ",")
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s=%s\n", property, value)
+	}
+	deviceId, err := results.DeviceId()
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(&b, "DeviceId=%s\n", deviceId)
+	return b.String(), nil
+}
+
+// ManagerTarget detects with a resource manager and reloads it from its
+// original file.
+type ManagerTarget struct {
+	Manager    *dd.ResourceManager
+	Properties []string
+}
+
+func (m *ManagerTarget) Detect(record map[string]string) (string, error) {
+	evidence := dd_example.ExtractEvidence(dd_example.ConvertEvidenceMap(record))
+	defer evidence.Free()
+	results := dd_example.NewTrackedResults(m.Manager, uint32(evidence.Count()), 0)
+	defer results.Free()
+	if err := results.MatchEvidence(evidence.Evidence); err != nil {
+		return "", err
+	}
+	return ResultFingerprint(results.ResultsHash, m.Properties)
+}
+
+func (m *ManagerTarget) Reload() error {
+	return m.Manager.ReloadFromOriginalFile()
+}
+
+// AsyncReloadTarget is a Target whose reloads happen after Reload has
+// returned, so can only report whether they failed later. Run adds the
+// errors returned by ReloadErrors at the end of the run to its report.
+type AsyncReloadTarget interface {
+	Target
+	ReloadErrors() []error
+}
+
+// EngineLog is a logger for an on-premise engine, set with
+// onpremise.WithCustomLogger, which records the reloads which the engine
+// failed to perform. Messages are passed on to Next if it is set.
+type EngineLog struct {
+	Next onpremise.LogWriter
+
+	mu     sync.Mutex
+	errors []error
+}
+
+// Prefixes of the messages an engine logs when a reload fails. These are the
+// messages of the onpremise package of device-detection-go v4.4.35 logged by
+// Engine.handleFileExternallyChanged, "failed to handle file externally
+// changed: %v", and by the file watcher, "Error watching file: %v". They must
+// be checked when the library is updated, as failures logged with a changed
+// message would no longer be reported.
+var engineReloadFailures = []string{
+	"failed to handle file externally changed",
+	"Error watching file",
+}
+
+func (l *EngineLog) Printf(format string, v ...interface{}) {
+	message := fmt.Sprintf(format, v...)
+	for _, prefix := range engineReloadFailures {
+		if strings.HasPrefix(message, prefix) {
+			l.mu.Lock()
+			l.errors = append(l.errors, errors.New(message))
+			l.mu.Unlock()
+			break
+		}
+	}
+	if l.Next != nil {
+		l.Next.Printf(format, v...)
+	}
+}
+
+// ReloadErrors returns the reload failures logged so far.
+func (l *EngineLog) ReloadErrors() []error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]error(nil), l.errors...)
+}
+
+// EngineTarget detects with an on-premise engine. The engine must watch its
+// data file, as it is reloaded by updating the modification time of the file
+// in the same way as the reload_from_file example. The reload itself happens
+// later in the file watcher of the engine, so Reload only fails if the file
+// cannot be touched. Failures of the engine to reload are reported through
+// Log, which must be the custom logger of the engine. Without a Log, the
+// reloads of the report are only the reloads requested.
+type EngineTarget struct {
+	Engine     *onpremise.Engine
+	DataFile   string
+	Properties []string
+	Log        *EngineLog
+}
+
+func (e *EngineTarget) Detect(record map[string]string) (string, error) {
+	results, err := e.Engine.Process(common.ConvertToEvidence(record))
+	if err != nil {
+		return "", err
+	}
+	defer results.Free()
+	return ResultFingerprint(results, e.Properties)
+}
+
+func (e *EngineTarget) Reload() error {
+	now := time.Now()
+	return os.Chtimes(e.DataFile, now, now)
+}
+
+func (e *EngineTarget) ReloadErrors() []error {
+	if e.Log == nil {
+		return nil
+	}
+	return e.Log.ReloadErrors()
+}
+
+// Options control a stress run. Reloads are performed at random
+// intervals between MinReload and MaxReload until Duration has passed.
+type Options struct {
+	Workers     int
+	Duration    time.Duration
+	MinReload   time.Duration
+	MaxReload   time.Duration
+	Seed        int64
+	MaxFailures int
+}
+
+// DefaultOptions returns the options used by the stress tests.
+func DefaultOptions() Options {
+	return Options{
+		Workers:     4,
+		Duration:    2 * time.Second,
+		MinReload:   10 * time.Millisecond,
+		MaxReload:   200 * time.Millisecond,
+		Seed:        1,
+		MaxFailures: 10,
+	}
+}
+
+// Failure is a detection which either failed or was torn, i.e. did not
+// return the value expected for the record.
+type Failure struct {
+	Record   int
+	Expected string
+	Actual   string
+	Err      error
+}
+
+func (f Failure) String() string {
+	if f.Err != nil {
+		return fmt.Sprintf("record %d: failed: %v", f.Record, f.Err)
+	}
+	return fmt.Sprintf("record %d: torn:\n%s", f.Record,
+		dd_example.DiffLines(f.Expected, f.Actual))
+}
+
+// Report is the outcome of a stress run. Failures holds up to
+// Options.MaxFailures of the failed and torn detections.
+type Report struct {
+	Detections     uint64
+	Failed         uint64
+	Torn           uint64
+	Records        int
+	Reloads        int
+	ReloadFailures int
+	Elapsed        time.Duration
+	Failures       []Failure
+	// Errors of the reloads of an AsyncReloadTarget which failed
+	ReloadErrors []error
+}
+
+// Ok returns true if every detection returned the expected value and every
+// reload succeeded.
+func (r *Report) Ok() bool {
+	return r.Failed == 0 && r.Torn == 0 && r.ReloadFailures == 0
+}
+
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Performed %d detections of %d records in %v.\n",
+		r.Detections, r.Records, r.Elapsed.Round(time.Millisecond))
+	fmt.Fprintf(&b, "Reloaded %d times, failed to reload %d times.\n",
+		r.Reloads, r.ReloadFailures)
+	fmt.Fprintf(&b, "Failed detections: %d, torn detections: %d.\n",
+		r.Failed, r.Torn)
+	for _, f := range r.Failures {
+		fmt.Fprintln(&b, f)
+	}
+	for _, err := range r.ReloadErrors {
+		fmt.Fprintf(&b, "reload failed: %v\n", err)
+	}
+	return b.String()
+}
+
+// Run performs detections of the records with a number of goroutines,
+// while the target is reloaded at random intervals. The value expected for
+// each record is detected before the run starts, and each detection during
+// the run is checked against it. An error is only returned if the expected
+// values cannot be detected. Reloads counts the calls to Reload which did not
+// fail, so for an AsyncReloadTarget it includes reloads which failed later.
+func Run(
+	target Target,
+	records []map[string]string,
+	options Options) (*Report, error) {
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no records to detect")
+	}
+	expected := make([]string, len(records))
+	for i, record := range records {
+		value, err := target.Detect(record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to detect record %d: %w", i, err)
+		}
+		expected[i] = value
+	}
+
+	report := &Report{Records: len(records)}
+	var mu sync.Mutex
+	fail := func(f Failure) {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(report.Failures) < options.MaxFailures {
+			report.Failures = append(report.Failures, f)
+		}
+	}
+
+	start := time.Now()
+	var stopped int32
+	var wg sync.WaitGroup
+	workers := options.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			// Each worker starts at a different record so that different
+			// records are detected at the same time.
+			for i := w * len(records) / workers; atomic.LoadInt32(&stopped) == 0; i++ {
+				i %= len(records)
+				actual, err := target.Detect(records[i])
+				atomic.AddUint64(&report.Detections, 1)
+				if err != nil {
+					atomic.AddUint64(&report.Failed, 1)
+					fail(Failure{Record: i, Err: err})
+				} else if actual != expected[i] {
+					atomic.AddUint64(&report.Torn, 1)
+					fail(Failure{
+						Record:   i,
+						Expected: expected[i],
+						Actual:   actual})
+				}
+			}
+		}(w)
+	}
+
+	random := rand.New(rand.NewSource(options.Seed))
+	for time.Since(start) < options.Duration {
+		wait := options.MinReload
+		if options.MaxReload > options.MinReload {
+			wait += time.Duration(random.Int63n(int64(options.MaxReload - options.MinReload)))
+		}
+		time.Sleep(wait)
+		if err := target.Reload(); err != nil {
+			report.ReloadFailures++
+		} else {
+			report.Reloads++
+		}
+	}
+	atomic.StoreInt32(&stopped, 1)
+	wg.Wait()
+	report.Elapsed = time.Since(start)
+
+	// Reloads which failed after Reload returned
+	if async, ok := target.(AsyncReloadTarget); ok {
+		report.ReloadErrors = async.ReloadErrors()
+		report.ReloadFailures += len(report.ReloadErrors)
+	}
+	return report, nil
+}
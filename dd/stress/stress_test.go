@@ -0,0 +1,236 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package stress
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	dd_example "github.com/51Degrees/device-detection-examples-go/v4/dd"
+	"github.com/51Degrees/device-detection-examples-go/v4/dd/internal/testutil"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+	"github.com/51Degrees/device-detection-go/v4/onpremise"
+)
+
+func TestMain(m *testing.M) {
+	dd_example.EnableLeakTracking(true)
+	testutil.Main(m, dd_example.ReportLeaks)
+}
+
+// fakeTarget returns the User-Agent of a record, suffixed with the number of
+// reloads if torn, or an error after a reload if failing.
+type fakeTarget struct {
+	reloads int32
+	torn    bool
+	failing bool
+}
+
+func (f *fakeTarget) Detect(record map[string]string) (string, error) {
+	reloads := atomic.LoadInt32(&f.reloads)
+	if f.failing && reloads > 0 {
+		return "", errors.New("reloading")
+	}
+	if f.torn && reloads%2 == 1 {
+		return record["header.user-agent"] + "*", nil
+	}
+	return record["header.user-agent"], nil
+}
+
+func (f *fakeTarget) Reload() error {
+	atomic.AddInt32(&f.reloads, 1)
+	return nil
+}
+
+// asyncTarget is a fakeTarget whose reloads fail after Reload has returned.
+type asyncTarget struct {
+	fakeTarget
+	log EngineLog
+}
+
+func (a *asyncTarget) Reload() error {
+	a.fakeTarget.Reload()
+	a.log.Printf("failed to handle file externally changed: %v", "corrupt")
+	a.log.Printf("data file loaded from %s", "file")
+	return nil
+}
+
+func (a *asyncTarget) ReloadErrors() []error {
+	return a.log.ReloadErrors()
+}
+
+// stressRecords returns the Evidence Records entries of the fixtures.
+func stressRecords(t *testing.T) []map[string]string {
+	fixtures, err := dd_example.Fixtures()
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := make([]map[string]string, len(fixtures))
+	for i, f := range fixtures {
+		records[i] = f.Record
+	}
+	return records
+}
+
+// Test that torn and failed detections are counted and reported.
+func TestRun(t *testing.T) {
+	options := DefaultOptions()
+	options.Duration = 100 * time.Millisecond
+	options.MinReload = time.Millisecond
+	options.MaxReload = 5 * time.Millisecond
+	options.MaxFailures = 3
+	records := stressRecords(t)
+
+	testData := []struct {
+		target *fakeTarget
+		ok     bool
+	}{
+		{&fakeTarget{}, true},
+		{&fakeTarget{torn: true}, false},
+		{&fakeTarget{failing: true}, false},
+	}
+	for _, data := range testData {
+		report, err := Run(data.target, records, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Ok() != data.ok {
+			t.Errorf("Expected ok to be %v for %+v, but got report:\n%s",
+				data.ok, *data.target, report)
+		}
+		if report.Detections == 0 || report.Reloads == 0 {
+			t.Errorf("Expected detections and reloads, but got report:\n%s",
+				report)
+		}
+		if data.target.torn && report.Torn == 0 {
+			t.Errorf("Expected torn detections, but got report:\n%s", report)
+		}
+		if data.target.failing && report.Failed == 0 {
+			t.Errorf("Expected failed detections, but got report:\n%s", report)
+		}
+		if len(report.Failures) > options.MaxFailures {
+			t.Errorf("Expected at most %d failures, but got %d",
+				options.MaxFailures, len(report.Failures))
+		}
+	}
+}
+
+// Test that the reload failures logged by an engine are reported.
+func TestRunAsyncReload(t *testing.T) {
+	options := DefaultOptions()
+	options.Duration = 50 * time.Millisecond
+	options.MinReload = time.Millisecond
+	options.MaxReload = 5 * time.Millisecond
+	target := &asyncTarget{}
+
+	report, err := Run(target, stressRecords(t), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Ok() || report.Reloads == 0 ||
+		report.ReloadFailures != report.Reloads ||
+		len(report.ReloadErrors) != report.Reloads {
+		t.Errorf("Expected every reload to fail, but got report:\n%s", report)
+	}
+}
+
+// Test that detections with a resource manager are consistent while it is
+// reloaded. Run with -race to also check for data races.
+func TestStressManager(t *testing.T) {
+	filePath := testutil.DataFilePathOrSkip(t)
+	manager := dd_example.NewTrackedManager()
+	config := dd.NewConfigHash(dd.InMemory)
+	config.SetUseUpperPrefixHeaders(false)
+	if err := dd.InitManagerFromFile(
+		manager.ResourceManager, *config, "", filePath); err != nil {
+		t.Fatalf("Failed to initialize resource manager. %v", err)
+	}
+	defer manager.Free()
+
+	report, err := Run(
+		&ManagerTarget{Manager: manager.ResourceManager},
+		stressRecords(t),
+		DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Ok() {
+		t.Error(report)
+	}
+	t.Log(report)
+}
+
+// Test that detections with an on-premise engine are consistent while its
+// data file is reloaded by the file watcher. A copy of the data file is used
+// so that the original is not changed.
+func TestStressEngine(t *testing.T) {
+	filePath := testutil.DataFilePathOrSkip(t)
+	dataFile := filepath.Join(t.TempDir(), filepath.Base(filePath))
+	if err := copyFile(filePath, dataFile); err != nil {
+		t.Fatal(err)
+	}
+	log := &EngineLog{}
+	engine, err := onpremise.New(
+		onpremise.WithDataFile(dataFile),
+		onpremise.WithAutoUpdate(false),
+		onpremise.WithFileWatch(true),
+		onpremise.WithCustomLogger(log))
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+	defer engine.Stop()
+
+	report, err := Run(
+		&EngineTarget{Engine: engine, DataFile: dataFile, Log: log},
+		stressRecords(t),
+		DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Ok() {
+		t.Error(report)
+	}
+	t.Log(report)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -23,12 +23,132 @@
 package main
 
 /*
-TODO: To be implemented
+This example illustrates how to get property values as Go types rather than
+parsing the strings returned by ValuesString.
+
+Each getter of DeviceResults returns a typed value, or an error explaining why
+there is no value, e.g. a *dd_example.NoValueError with the reason given by the
+engine when the property has no value for the evidence.
+
+To run this example, perform the following command:
+```
+go run strongly_typed.go
+```
 */
 
-// import "fmt"
-// import "github.com/51Degrees/device-detection-go/ddonpremise"
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	dd_example "github.com/51Degrees/device-detection-examples-go/v4/dd"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+// describe returns a description of a typed value or the reason there is no
+// value.
+func describe(value interface{}, err error) string {
+	var noValue *dd_example.NoValueError
+	if errors.As(err, &noValue) {
+		return fmt.Sprintf("no value (%s)", noValue.Reason)
+	} else if err != nil {
+		dd_example.Fatalln(err)
+	}
+	return fmt.Sprint(value)
+}
+
+// function match performs a match on an input User-Agent string and returns
+// typed property values as an output string.
+func match(
+	results *dd.ResultsHash,
+	ua string) string {
+	// Perform detection
+	err := results.MatchUserAgent(ua)
+	if err != nil {
+		dd_example.Fatalln(err)
+	}
+	device := dd_example.NewDeviceResults(results)
+
+	// IsMobile is a bool, so can be used directly in a condition
+	isMobile, err := device.IsMobile()
+	if err != nil {
+		dd_example.Fatalln(err)
+	}
+	returnStr := fmt.Sprintf("\tIsMobile: %t\n", isMobile)
+
+	// Other values are logged as they vary between data files
+	width, err := device.ScreenPixelsWidth()
+	log.Printf("ScreenPixelsWidth (int): %s\n", describe(width, err))
+	names, err := device.HardwareName()
+	log.Printf("HardwareName ([]string): %s\n", describe(names, err))
+	version, err := device.BrowserVersion()
+	log.Printf("BrowserVersion (Version): %s\n", describe(version, err))
+	if err == nil && version.Major() >= 40 {
+		log.Printf("Browser version %s is at least 40\n", version)
+	}
+
+	return returnStr
+}
+
+func runStronglyTyped(perf dd.PerformanceProfile) string {
+	// Initialise manager
+	manager := dd_example.NewTrackedManager()
+	config := dd.NewConfigHash(perf)
+	filePath := dd_example.GetFilePathByName([]string{dd_example.LiteDataFile})
+
+	err := dd_example.InitManagerFromFile(
+		manager.ResourceManager,
+		*config,
+		"IsMobile,ScreenPixelsWidth,HardwareName,BrowserVersion",
+		filePath)
+	if err != nil {
+		dd_example.Fatalln(err)
+	}
+
+	// Make sure manager object will be freed after the function execution
+	defer manager.Free()
+
+	// Create results
+	results := dd_example.NewTrackedResults(manager.ResourceManager, 1, 0)
+
+	// Make sure results object is freed after function execution.
+	defer results.Free()
+
+	// User-Agent string of an iPhone mobile device.
+	const uaMobile = "Mozilla/5.0 (iPhone; CPU iPhone OS 7_1 like Mac OS X) " +
+		"AppleWebKit/537.51.2 (KHTML, like Gecko) Version/7.0 Mobile/11D167 " +
+		"Safari/9537.53"
+
+	// User-Agent string of Firefox Web browser version 41 on desktop.
+	const uaDesktop = "Mozilla/5.0 (Windows NT 6.3; WOW64; rv:41.0) " +
+		"Gecko/20100101 Firefox/41.0"
+
+	// Perform detection on mobile User-Agent
+	actual := fmt.Sprintf("Mobile User-Agent: %s\n", uaMobile)
+	actual += match(results.ResultsHash, uaMobile)
+
+	// Perform detection on desktop User-Agent
+	actual += fmt.Sprintf("\nDesktop User-Agent: %s\n", uaDesktop)
+	actual += match(results.ResultsHash, uaDesktop)
+
+	// Expected output
+	expected := "Mobile User-Agent: Mozilla/5.0 (iPhone; CPU iPhone OS 7_1 like Mac OS X) AppleWebKit/537.51.2 (KHTML, like Gecko) Version/7.0 Mobile/11D167 Safari/9537.53\n"
+	expected += "\tIsMobile: true\n"
+	expected += "\n"
+	expected += "Desktop User-Agent: Mozilla/5.0 (Windows NT 6.3; WOW64; rv:41.0) Gecko/20100101 Firefox/41.0\n"
+	expected += "\tIsMobile: false\n"
+	if actual != expected {
+		log.Println("Expected:")
+		log.Println(expected)
+		log.Println("")
+		log.Println("Actual:")
+		log.Println(actual)
+		dd_example.Fatalln("Output does not match expected.")
+	}
+	return actual
+}
 
 func main() {
-	// TODO: To be implemented
+	dd_example.PerformExample(dd.Default, runStronglyTyped)
 }
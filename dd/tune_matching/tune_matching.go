@@ -0,0 +1,336 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package main
+
+/*
+This example illustrates how to choose the matching settings of the Hash
+engine for a corpus of Evidence Records, such as traffic captured by the web
+examples.
+
+Every combination of the Drift, Difference and graphs given is used to process
+the corpus, and compared with a reference configuration which uses the default
+settings of the performance profile. For each combination the following is
+reported:
+  - the number of detections per second,
+  - the share of records with a NONE match method,
+  - the share of records with a different device ID to the reference.
+
+Higher Drift and Difference allow more User-Agents to be matched, but each
+match is less certain, so the recommendation is the combination with the
+fewest NONE matches which changes no more than `-max-changed` of the results
+of the reference. Ties are broken by throughput.
+
+As the corpus is processed once for every combination, only the first 5000
+records are used by default. Use `-records 0` to process all of them.
+
+To run this example, perform the following command:
+```
+go run tune_matching.go -e "../20000 Evidence Records.yml"
+```
+*/
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	dd_example "github.com/51Degrees/device-detection-examples-go/v4/dd"
+	"gopkg.in/yaml.v3"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+// Number of records processed by default, bounding the time taken as the
+// records are processed once for every combination of settings.
+const defaultRecords = 5000
+
+type options struct {
+	DataFilePath     string
+	EvidenceFilePath string
+	Records          int
+	Drifts           string
+	Differences      string
+	Graphs           string
+	MaxChanged       float64
+	showHelp         bool
+}
+
+func parseOptions() options {
+	o := options{}
+
+	flag.StringVar(&o.DataFilePath, "data-file", "../"+dd_example.LiteDataFile, "Path to a 51Degrees Hash data file")
+	flag.StringVar(&o.DataFilePath, "d", o.DataFilePath, "Alias for -data-file")
+
+	flag.StringVar(&o.EvidenceFilePath, "evidence-file", "../"+dd_example.EvidenceFileYaml, "Path to a Evidence Records YAML file")
+	flag.StringVar(&o.EvidenceFilePath, "e", o.EvidenceFilePath, "Alias for -evidence-file")
+
+	flag.IntVar(&o.Records, "records", defaultRecords, "Maximum number of records to process. All records if 0")
+	flag.StringVar(&o.Drifts, "drift", "0,1,2,5", "Comma separated list of Drift values")
+	flag.StringVar(&o.Differences, "difference", "0,10,50,100", "Comma separated list of Difference values")
+	flag.StringVar(&o.Graphs, "graphs", "performance,predictive,both", "Comma separated list of graphs to use: 'performance', 'predictive' or 'both'")
+	flag.Float64Var(&o.MaxChanged, "max-changed", 1, "Maximum percentage of device IDs changed from the reference for a setting to be recommended")
+
+	flag.BoolVar(&o.showHelp, "help", false, "Print help")
+	flag.BoolVar(&o.showHelp, "h", o.showHelp, "Alias for -help")
+
+	flag.Parse()
+	return o
+}
+
+// setting is a combination of matching settings.
+type setting struct {
+	drift       int32
+	difference  int32
+	performance bool
+	predictive  bool
+}
+
+// apply sets the matching settings of a configuration.
+func (s setting) apply(config *dd.ConfigHash) {
+	config.SetDrift(s.drift)
+	config.SetDifference(s.difference)
+	config.SetUsePerformanceGraph(s.performance)
+	config.SetUsePredictiveGraph(s.predictive)
+}
+
+// graphsName returns the name of the graphs used by a setting.
+func graphsName(performance, predictive bool) string {
+	switch {
+	case performance && predictive:
+		return "both"
+	case performance:
+		return "performance"
+	case predictive:
+		return "predictive"
+	}
+	return "none"
+}
+
+// outcome of processing the corpus with a setting.
+type outcome struct {
+	setting
+	perSecond float64
+	none      float64
+	changed   float64
+	deviceIds []string
+}
+
+// parseInts parses a comma separated list of integers.
+func parseInts(name, s string) []int32 {
+	values := make([]int32, 0)
+	for _, p := range strings.Split(s, ",") {
+		n, err := strconv.ParseInt(strings.TrimSpace(p), 10, 32)
+		if err != nil || n < 0 {
+			dd_example.Fatalf("ERROR: Invalid %s value \"%s\".\n", name, p)
+		}
+		values = append(values, int32(n))
+	}
+	return values
+}
+
+// grid returns every combination of the settings given.
+func grid(drifts, differences []int32, graphs []string) []setting {
+	settings := make([]setting, 0)
+	for _, g := range graphs {
+		g = strings.TrimSpace(g)
+		if g != "performance" && g != "predictive" && g != "both" {
+			dd_example.Fatalf("ERROR: Invalid graphs \"%s\".\n", g)
+		}
+		for _, drift := range drifts {
+			for _, difference := range differences {
+				settings = append(settings, setting{
+					drift:       drift,
+					difference:  difference,
+					performance: g != "predictive",
+					predictive:  g != "performance",
+				})
+			}
+		}
+	}
+	return settings
+}
+
+// readRecords reads up to max records from an Evidence Records file, or all
+// records if max is 0.
+func readRecords(evidenceFilePath string, max int) []map[string]string {
+	file, err := os.Open(evidenceFilePath)
+	if err != nil {
+		dd_example.Fatalf("ERROR: Failed to open file \"%s\".\n", evidenceFilePath)
+	}
+	defer file.Close()
+
+	records := make([]map[string]string, 0)
+	dec := yaml.NewDecoder(file)
+	for max == 0 || len(records) < max {
+		// Decode Evidence file by line
+		var doc map[string]string
+		if err := dec.Decode(&doc); err == io.EOF {
+			break
+		} else if err != nil {
+			dd_example.Fatalf("ERROR: Failed during decoding file \"%s\". %v\n", evidenceFilePath, err)
+		}
+		records = append(records, doc)
+	}
+	return records
+}
+
+// run processes the records with a configuration and returns the outcome.
+// The device IDs of the reference are used to count changed results, unless
+// reference is nil.
+func run(
+	filePath string,
+	config *dd.ConfigHash,
+	s setting,
+	records []map[string]string,
+	reference []string) outcome {
+	manager := dd_example.NewTrackedManager()
+	err := dd.InitManagerFromFile(manager.ResourceManager, *config, "", filePath)
+	if err != nil {
+		dd_example.Fatalln(err)
+	}
+	defer manager.Free()
+
+	o := outcome{setting: s, deviceIds: make([]string, len(records))}
+	var total, none, changed uint64
+	start := time.Now()
+	for i, record := range records {
+		evidence := dd_example.ExtractEvidence(dd_example.ConvertEvidenceMap(record))
+		results := dd_example.NewTrackedResults(manager.ResourceManager, uint32(evidence.Count()), 0)
+		if err := results.MatchEvidence(evidence.Evidence); err != nil {
+			dd_example.Fatalf("ERROR: Failed to perform detection. %v\n", err)
+		}
+		if o.deviceIds[i], err = results.DeviceId(); err != nil {
+			dd_example.Fatalf("ERROR: Failed to get device id. %v\n", err)
+		}
+
+		// Weight rates by the number of times the record occurred
+		weight := dd_example.RecordCount(record)
+		total += weight
+		if results.Method() == dd.None {
+			none += weight
+		}
+		if reference != nil && o.deviceIds[i] != reference[i] {
+			changed += weight
+		}
+		results.Free()
+		evidence.Free()
+	}
+	elapsed := time.Since(start)
+
+	o.perSecond = float64(len(records)) / elapsed.Seconds()
+	if total > 0 {
+		o.none = float64(none) / float64(total) * 100
+		o.changed = float64(changed) / float64(total) * 100
+	}
+	return o
+}
+
+// recommend returns the index of the outcome with the fewest NONE matches
+// which changes no more than maxChanged percent of the results, or -1 if
+// there is none.
+func recommend(outcomes []outcome, maxChanged float64) int {
+	best := -1
+	for i, o := range outcomes {
+		if o.changed > maxChanged {
+			continue
+		}
+		if best < 0 || o.none < outcomes[best].none ||
+			(o.none == outcomes[best].none && o.perSecond > outcomes[best].perSecond) {
+			best = i
+		}
+	}
+	return best
+}
+
+func main() {
+	defer dd_example.ReportLeaksAtExit()
+
+	o := parseOptions()
+	if o.showHelp {
+		flag.Usage()
+		return
+	}
+
+	settings := grid(
+		parseInts("drift", o.Drifts),
+		parseInts("difference", o.Differences),
+		strings.Split(o.Graphs, ","))
+
+	filePath := dd_example.GetFilePathByPath(o.DataFilePath)
+	evidenceFilePath := dd_example.GetFilePathByPath(o.EvidenceFilePath)
+	records := readRecords(evidenceFilePath, o.Records)
+	fmt.Printf("Processing %d records with %d settings.\n\n", len(records), len(settings))
+
+	// Reference configuration with the default settings
+	config := dd.NewConfigHash(dd.Balanced)
+	referenceSetting := setting{
+		drift:       config.Drift(),
+		difference:  config.Difference(),
+		performance: config.UsePerformanceGraph(),
+		predictive:  config.UsePredictiveGraph(),
+	}
+	reference := run(filePath, config, referenceSetting, records, nil)
+
+	outcomes := make([]outcome, len(settings))
+	for i, s := range settings {
+		config := dd.NewConfigHash(dd.Balanced)
+		s.apply(config)
+		outcomes[i] = run(filePath, config, s, records, reference.deviceIds)
+	}
+	best := recommend(outcomes, o.MaxChanged)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Graphs\tDrift\tDifference\tDetections/s\tNONE\tChanged\t\t")
+	for i, oc := range append([]outcome{reference}, outcomes...) {
+		mark := ""
+		if i == 0 {
+			mark = "reference"
+		} else if i-1 == best {
+			mark = "recommended"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.0f\t%.2f%%\t%.2f%%\t%s\t\n",
+			graphsName(oc.performance, oc.predictive),
+			oc.drift,
+			oc.difference,
+			oc.perSecond,
+			oc.none,
+			oc.changed,
+			mark)
+	}
+	tw.Flush()
+
+	if best < 0 {
+		fmt.Printf("\nEvery setting changes more than %.2f%% of the results of "+
+			"the reference.\n", o.MaxChanged)
+		return
+	}
+	b := outcomes[best]
+	fmt.Printf("\nRecommended: SetDrift(%d), SetDifference(%d), "+
+		"SetUsePerformanceGraph(%v), SetUsePredictiveGraph(%v)\n",
+		b.drift, b.difference, b.performance, b.predictive)
+}
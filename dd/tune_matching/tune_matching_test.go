@@ -0,0 +1,60 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package main
+
+import "testing"
+
+// Test that the grid contains every combination of the settings given.
+func TestGrid(t *testing.T) {
+	settings := grid([]int32{0, 1}, []int32{0, 10, 50}, []string{"performance", " both"})
+	if len(settings) != 12 {
+		t.Fatalf("Expected 12 settings, but got %d", len(settings))
+	}
+	first, last := settings[0], settings[len(settings)-1]
+	if !first.performance || first.predictive || first.drift != 0 || first.difference != 0 {
+		t.Errorf("Unexpected first setting %+v", first)
+	}
+	if !last.performance || !last.predictive || last.drift != 1 || last.difference != 50 {
+		t.Errorf("Unexpected last setting %+v", last)
+	}
+}
+
+// Test that the recommendation has the fewest NONE matches within the limit
+// of changed results, preferring higher throughput.
+func TestRecommend(t *testing.T) {
+	outcomes := []outcome{
+		{none: 5, changed: 0, perSecond: 100},
+		{none: 1, changed: 3, perSecond: 100},
+		{none: 2, changed: 0.5, perSecond: 50},
+		{none: 2, changed: 1, perSecond: 80},
+	}
+	if best := recommend(outcomes, 1); best != 3 {
+		t.Errorf("Expected outcome 3 to be recommended, but got %d", best)
+	}
+	if best := recommend(outcomes, 0.1); best != 0 {
+		t.Errorf("Expected outcome 0 to be recommended, but got %d", best)
+	}
+	if best := recommend(outcomes[1:2], 1); best != -1 {
+		t.Errorf("Expected no recommendation, but got %d", best)
+	}
+}
@@ -0,0 +1,216 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package main
+
+/*
+This example illustrates how an Evidence Records file can be checked before it
+is used for a long running batch job. Every problem found is reported with its
+line, column and the index of the record it belongs to, in the format:
+```
+[file]:[line]:[column]: record [index]: [problem]
+```
+The column is omitted for YAML syntax errors.
+
+The following problems are reported:
+  - YAML syntax errors, after which the rest of the file cannot be read,
+  - records which are not a mapping of keys to values,
+  - values which are not strings, e.g. numbers, lists or nulls,
+  - keys without a known prefix (header, query, cookie, server or meta),
+  - empty values,
+  - duplicate keys within a record,
+  - values longer than `-max-value-length`.
+
+The program exits with a non-zero code if any problem is found.
+
+To run this example, perform the following command:
+```
+go run validate_evidence.go -e "../20000 Evidence Records.yml"
+```
+*/
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	dd_example "github.com/51Degrees/device-detection-examples-go/v4/dd"
+	"gopkg.in/yaml.v3"
+)
+
+// Prefixes an Evidence Record key can have
+var knownPrefixes = map[string]bool{
+	"header":              true,
+	"query":               true,
+	"cookie":              true,
+	"server":              true,
+	dd_example.MetaPrefix: true,
+}
+
+// Pattern of the line number in a YAML error message
+var yamlErrorLine = regexp.MustCompile(`^yaml: line (\d+): `)
+
+// problem found in an Evidence Records file
+type problem struct {
+	line    int
+	column  int
+	record  int
+	message string
+}
+
+// String returns the problem as "line:column: record index: message". The
+// column is omitted if it is not known.
+func (p problem) String() string {
+	if p.column == 0 {
+		return fmt.Sprintf("%d: record %d: %s", p.line, p.record, p.message)
+	}
+	return fmt.Sprintf("%d:%d: record %d: %s", p.line, p.column, p.record, p.message)
+}
+
+// validateRecord checks a single decoded record and returns the problems
+// found in it.
+func validateRecord(doc *yaml.Node, record int, maxValueLength int) []problem {
+	var problems []problem
+	report := func(n *yaml.Node, format string, args ...interface{}) {
+		problems = append(problems, problem{
+			n.Line, n.Column, record, fmt.Sprintf(format, args...)})
+	}
+
+	node := doc
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			report(node, "record is empty")
+			return problems
+		}
+		node = node.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+			report(node, "record is empty")
+		} else {
+			report(node, "record is not a mapping of keys to values")
+		}
+		return problems
+	}
+	if len(node.Content) == 0 {
+		report(node, "record is empty")
+		return problems
+	}
+
+	seen := make(map[string]*yaml.Node)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Kind != yaml.ScalarNode {
+			report(key, "key is not a string")
+			continue
+		}
+
+		// Header names are case insensitive so differ only in case
+		name := key.Value
+		prefix, rest, found := strings.Cut(name, ".")
+		if !found || !knownPrefixes[prefix] || rest == "" {
+			report(key, "key '%s' does not have a known prefix", name)
+		}
+		unique := name
+		if prefix == "header" {
+			unique = strings.ToLower(name)
+		}
+		if first, ok := seen[unique]; ok {
+			report(key, "duplicate key '%s', first defined at line %d", name, first.Line)
+		} else {
+			seen[unique] = key
+		}
+
+		switch {
+		case value.Kind != yaml.ScalarNode:
+			report(value, "value of '%s' is not a string", name)
+		case value.Tag == "!!null":
+			report(value, "value of '%s' is empty", name)
+		case value.Tag != "!!str":
+			report(value, "value of '%s' is %s, not a string", name,
+				strings.TrimPrefix(value.Tag, "!!"))
+		case value.Value == "":
+			report(value, "value of '%s' is empty", name)
+		case maxValueLength > 0 && len(value.Value) > maxValueLength:
+			report(value, "value of '%s' is %d characters, longer than %d",
+				name, len(value.Value), maxValueLength)
+		}
+	}
+	return problems
+}
+
+// validate checks every record read from r and returns the problems found,
+// and the number of records read.
+func validate(r io.Reader, maxValueLength int) ([]problem, int) {
+	var problems []problem
+	dec := yaml.NewDecoder(r)
+	record := 0
+	for ; ; record++ {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			// The decoder cannot recover from a syntax error, so this is
+			// the last problem which can be reported.
+			p := problem{0, 0, record, err.Error()}
+			if m := yamlErrorLine.FindStringSubmatch(err.Error()); m != nil {
+				p.line, _ = strconv.Atoi(m[1])
+				p.message = strings.TrimPrefix(err.Error(), m[0])
+			}
+			problems = append(problems, p)
+			break
+		}
+		problems = append(problems, validateRecord(&doc, record, maxValueLength)...)
+	}
+	return problems, record
+}
+
+func main() {
+	var evidenceFilePath string
+	var maxValueLength int
+	flag.StringVar(&evidenceFilePath, "evidence-file", "../"+dd_example.EvidenceFileYaml, "Path to a Evidence Records YAML file")
+	flag.StringVar(&evidenceFilePath, "e", evidenceFilePath, "Alias for -evidence-file")
+	flag.IntVar(&maxValueLength, "max-value-length", 4096, "Maximum length of a value. No limit is applied if 0")
+	flag.Parse()
+
+	file, err := os.Open(evidenceFilePath)
+	if err != nil {
+		log.Fatalf("ERROR: Failed to open file \"%s\".\n", evidenceFilePath)
+	}
+	problems, records := validate(file, maxValueLength)
+	file.Close()
+
+	for _, p := range problems {
+		fmt.Printf("%s:%s\n", evidenceFilePath, p)
+	}
+	if len(problems) > 0 {
+		fmt.Printf("Found %d problems in %d records.\n", len(problems), records)
+		os.Exit(1)
+	}
+	fmt.Printf("No problems found in %d records.\n", records)
+}
@@ -0,0 +1,120 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	dd_example "github.com/51Degrees/device-detection-examples-go/v4/dd"
+)
+
+// Test that each kind of problem is reported at the right position and that
+// a valid file has no problems.
+func TestValidate(t *testing.T) {
+	testData := []struct {
+		yaml     string
+		records  int
+		expected []string
+	}{
+		{
+			"---\n" +
+				"header.user-agent: Mozilla/5.0\n" +
+				"query.sec-ch-ua: '\"Chromium\";v=\"124\"'\n" +
+				"meta.count: \"3\"\n" +
+				"---\n" +
+				"header.user-agent: curl/7.80.0\n" +
+				"...\n",
+			2,
+			nil,
+		},
+		{
+			"---\n" +
+				"header.user-agent: A\n" +
+				"header.User-Agent: B\n" +
+				"user-agent: C\n" +
+				"query.width: 1080\n" +
+				"---\n" +
+				"- header.user-agent\n" +
+				"---\n" +
+				"header.sec-ch-ua:\n" +
+				"header.sec-ch-ua-mobile: ''\n" +
+				"header.sec-ch-ua-model: [a, b]\n" +
+				"cookie.: x\n" +
+				"server.client-ip: '" + strings.Repeat("1", 65) + "'\n",
+			3,
+			[]string{
+				"3:1: record 0: duplicate key 'header.User-Agent', first defined at line 2",
+				"4:1: record 0: key 'user-agent' does not have a known prefix",
+				"5:14: record 0: value of 'query.width' is int, not a string",
+				"7:1: record 1: record is not a mapping of keys to values",
+				"9:18: record 2: value of 'header.sec-ch-ua' is empty",
+				"10:26: record 2: value of 'header.sec-ch-ua-mobile' is empty",
+				"11:25: record 2: value of 'header.sec-ch-ua-model' is not a string",
+				"12:1: record 2: key 'cookie.' does not have a known prefix",
+				"13:19: record 2: value of 'server.client-ip' is 65 characters, longer than 64",
+			},
+		},
+		{
+			"---\n" +
+				"header.user-agent: A\n" +
+				"---\n" +
+				"header.user-agent: \"B\n" +
+				"  header.x: : y\n",
+			1,
+			[]string{
+				"4: record 1: found unexpected end of stream",
+			},
+		},
+	}
+
+	for i, data := range testData {
+		problems, records := validate(strings.NewReader(data.yaml), 64)
+		if records != data.records {
+			t.Errorf("Test %d: expected '%d' records, but got '%d'",
+				i, data.records, records)
+		}
+		if len(problems) != len(data.expected) {
+			t.Errorf("Test %d: expected '%d' problems, but got %v",
+				i, len(data.expected), problems)
+			continue
+		}
+		for j, p := range problems {
+			if p.String() != data.expected[j] {
+				t.Errorf("Test %d: expected '%s', but got '%s'",
+					i, data.expected[j], p)
+			}
+		}
+	}
+}
+
+// Test that the embedded fixture corpus is valid.
+func TestValidateFixtures(t *testing.T) {
+	problems, records := validate(dd_example.FixtureEvidence(), 4096)
+	if records == 0 {
+		t.Error("Expected the fixtures to have records")
+	}
+	for _, p := range problems {
+		t.Errorf("Unexpected problem '%s'", p)
+	}
+}
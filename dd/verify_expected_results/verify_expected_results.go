@@ -0,0 +1,136 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package main
+
+/*
+This example illustrates how to verify a data file against a file of expected
+results, so that the detections which matter most, such as the top devices of
+a site, are pinned and any changes to them are caught when the data file is
+updated.
+
+The format of the expected results file is described in
+dd/expected_results.go, and expected_results.yml in this directory pins the
+detections of a few common User-Agents. The program reports each property
+which has no value, or whose detected value is not the one expected, and exits
+with status 1 if there are any.
+
+To run this example, perform the following command:
+```
+go run verify_expected_results.go -x expected_results.yml
+```
+*/
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	dd_example "github.com/51Degrees/device-detection-examples-go/v4/dd"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+type options struct {
+	DataFilePath     string
+	ExpectedFilePath string
+	showHelp         bool
+}
+
+func parseOptions() options {
+	o := options{}
+
+	flag.StringVar(&o.DataFilePath, "data-file", "../"+dd_example.LiteDataFile, "Path to a 51Degrees Hash data file")
+	flag.StringVar(&o.DataFilePath, "d", o.DataFilePath, "Alias for -data-file")
+
+	flag.StringVar(&o.ExpectedFilePath, "expected-file", "expected_results.yml", "Path to an expected results YAML file")
+	flag.StringVar(&o.ExpectedFilePath, "x", o.ExpectedFilePath, "Alias for -expected-file")
+
+	flag.BoolVar(&o.showHelp, "help", false, "Print help")
+	flag.BoolVar(&o.showHelp, "h", o.showHelp, "Alias for -help")
+
+	flag.Parse()
+	return o
+}
+
+// readExpectedResults reads the expected results from a file.
+func readExpectedResults(path string) ([]dd_example.ExpectedResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return dd_example.ReadExpectedResults(file)
+}
+
+// verify returns the mismatches of the detections made with a data file
+// against the expected results.
+func verify(dataFilePath string, expected []dd_example.ExpectedResult) []dd_example.Mismatch {
+	// Initialise manager
+	manager := dd_example.NewTrackedManager()
+	config := dd.NewConfigHash(dd.Balanced)
+	err := dd.InitManagerFromFile(
+		manager.ResourceManager,
+		*config,
+		"",
+		dataFilePath)
+	if err != nil {
+		dd_example.Fatalln(err)
+	}
+
+	// Make sure manager object will be freed after the function execution
+	defer manager.Free()
+
+	mismatches, err := dd_example.VerifyExpectedResults(manager.ResourceManager, expected)
+	if err != nil {
+		dd_example.Fatalf("ERROR: %v\n", err)
+	}
+	return mismatches
+}
+
+func main() {
+	defer dd_example.ReportLeaksAtExit()
+
+	o := parseOptions()
+	if o.showHelp {
+		flag.Usage()
+		return
+	}
+
+	expected, err := readExpectedResults(o.ExpectedFilePath)
+	if err != nil {
+		dd_example.Fatalf("ERROR: Failed to read expected results \"%s\". %v\n",
+			o.ExpectedFilePath, err)
+	}
+
+	mismatches := verify(dd_example.GetFilePathByPath(o.DataFilePath), expected)
+	for _, m := range mismatches {
+		fmt.Println(m)
+	}
+	fmt.Printf("Verified %d expected results, %d mismatches.\n",
+		len(expected), len(mismatches))
+	if len(mismatches) > 0 {
+		// Deferred calls do not run on exit
+		dd_example.ReportLeaksAtExit()
+		os.Exit(1)
+	}
+}
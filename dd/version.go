@@ -0,0 +1,228 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package dd_example
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a version number such as "124.0.6367.208" made of numeric
+// segments separated by dots. The zero value is an unknown version, which is
+// what the engine returns as "Unknown" when it cannot determine one.
+type Version struct {
+	segments []int
+}
+
+// Value the engine returns for a version it cannot determine
+const unknownVersion = "Unknown"
+
+// ParseVersion parses a version number of one or more numeric segments
+// separated by dots. "Unknown" parses to the unknown version.
+func ParseVersion(s string) (Version, error) {
+	if strings.EqualFold(strings.TrimSpace(s), unknownVersion) {
+		return Version{}, nil
+	}
+	parts := strings.Split(strings.TrimSpace(s), ".")
+	segments := make([]int, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("'%s' is not a valid version", s)
+		}
+		segments[i] = n
+	}
+	return Version{segments}, nil
+}
+
+// MustParseVersion is like ParseVersion but panics if the version is not
+// valid. It is intended for versions known at compile time.
+func MustParseVersion(s string) Version {
+	v, err := ParseVersion(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// IsUnknown returns true if the version is not known.
+func (v Version) IsUnknown() bool {
+	return len(v.segments) == 0
+}
+
+// Segments returns the numeric segments of the version.
+func (v Version) Segments() []int {
+	return append([]int(nil), v.segments...)
+}
+
+// Major returns the first segment of the version.
+func (v Version) Major() int {
+	if len(v.segments) == 0 {
+		return 0
+	}
+	return v.segments[0]
+}
+
+// Compare returns -1, 0 or 1 if the version is lower than, equal to or
+// higher than another. Missing segments are treated as 0, so "11" is equal
+// to "11.0". An unknown version is lower than all known versions.
+func (v Version) Compare(other Version) int {
+	if v.IsUnknown() || other.IsUnknown() {
+		switch {
+		case v.IsUnknown() && other.IsUnknown():
+			return 0
+		case v.IsUnknown():
+			return -1
+		}
+		return 1
+	}
+	n := len(v.segments)
+	if len(other.segments) > n {
+		n = len(other.segments)
+	}
+	for i := 0; i < n; i++ {
+		a, b := 0, 0
+		if i < len(v.segments) {
+			a = v.segments[i]
+		}
+		if i < len(other.segments) {
+			b = other.segments[i]
+		}
+		if a < b {
+			return -1
+		} else if a > b {
+			return 1
+		}
+	}
+	return 0
+}
+
+// AtLeast returns true if the version is known and equal to or higher than
+// another.
+func (v Version) AtLeast(other Version) bool {
+	return !v.IsUnknown() && v.Compare(other) >= 0
+}
+
+// Before returns true if the version is known and lower than another.
+func (v Version) Before(other Version) bool {
+	return !v.IsUnknown() && v.Compare(other) < 0
+}
+
+// String returns the version in dotted form, or "Unknown".
+func (v Version) String() string {
+	if v.IsUnknown() {
+		return unknownVersion
+	}
+	parts := make([]string, len(v.segments))
+	for i, s := range v.segments {
+		parts[i] = strconv.Itoa(s)
+	}
+	return strings.Join(parts, ".")
+}
+
+// Operators of a version range constraint, longest first so that ">=" is not
+// read as ">".
+var versionOperators = []string{">=", "<=", "!=", "==", ">", "<", "="}
+
+// versionConstraint is a single comparison such as ">=120".
+type versionConstraint struct {
+	op      string
+	version Version
+}
+
+func (c versionConstraint) matches(v Version) bool {
+	cmp := v.Compare(c.version)
+	switch c.op {
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	case "!=":
+		return cmp != 0
+	}
+	return cmp == 0
+}
+
+// VersionRange is a set of constraints which a version must all satisfy,
+// such as ">=120 <130".
+type VersionRange struct {
+	constraints []versionConstraint
+}
+
+// ParseVersionRange parses constraints separated by spaces or commas. Each
+// constraint is an operator of >=, >, <=, <, = or != followed by a version.
+// A version without an operator must be equal, so "17.2" matches "17.2.0".
+func ParseVersionRange(s string) (VersionRange, error) {
+	terms := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ' ' || r == ','
+	})
+	if len(terms) == 0 {
+		return VersionRange{}, fmt.Errorf("'%s' is not a valid version range", s)
+	}
+	r := VersionRange{make([]versionConstraint, 0, len(terms))}
+	for _, t := range terms {
+		op := "="
+		for _, o := range versionOperators {
+			if strings.HasPrefix(t, o) {
+				op = o
+				t = t[len(o):]
+				break
+			}
+		}
+		v, err := ParseVersion(t)
+		if err != nil || v.IsUnknown() {
+			return VersionRange{}, fmt.Errorf(
+				"'%s' is not a valid version range", s)
+		}
+		r.constraints = append(r.constraints, versionConstraint{op, v})
+	}
+	return r, nil
+}
+
+// Contains returns true if the version is known and satisfies all the
+// constraints of the range.
+func (r VersionRange) Contains(v Version) bool {
+	if v.IsUnknown() {
+		return false
+	}
+	for _, c := range r.constraints {
+		if !c.matches(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the constraints of the range separated by spaces.
+func (r VersionRange) String() string {
+	parts := make([]string, len(r.constraints))
+	for i, c := range r.constraints {
+		parts[i] = c.op + c.version.String()
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,157 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package dd_example
+
+import (
+	"testing"
+
+	"github.com/51Degrees/device-detection-examples-go/v4/dd/internal/testutil"
+	"github.com/51Degrees/device-detection-examples-go/v4/onpremise/common"
+
+	"github.com/51Degrees/device-detection-go/v4/onpremise"
+)
+
+// Test that versions compare by numeric segments and that unknown versions
+// are lower than all known ones.
+func TestVersionCompare(t *testing.T) {
+	testData := []struct {
+		a, b     string
+		expected int
+	}{
+		{"120", "120.0.0", 0},
+		{"124.0.6367.208", "120", 1},
+		{"9", "10", -1},
+		{"17.1.2", "17.2", -1},
+		{"Unknown", "1", -1},
+		{"1", "Unknown", 1},
+		{"Unknown", "unknown", 0},
+	}
+	for _, data := range testData {
+		actual := MustParseVersion(data.a).Compare(MustParseVersion(data.b))
+		if actual != data.expected {
+			t.Errorf("Expected '%s' compared to '%s' to be %d, but got %d",
+				data.a, data.b, data.expected, actual)
+		}
+	}
+
+	for _, invalid := range []string{"", "1..2", "v1", "1.-2", "17.2 beta"} {
+		if _, err := ParseVersion(invalid); err == nil {
+			t.Errorf("Expected '%s' not to be a valid version", invalid)
+		}
+	}
+}
+
+// Test that ranges match versions satisfying all their constraints, and never
+// match an unknown version.
+func TestVersionRange(t *testing.T) {
+	testData := []struct {
+		versionRange string
+		version      string
+		expected     bool
+	}{
+		{">=120", "124.0.6367.208", true},
+		{">=120", "119.9", false},
+		{"<17.2", "17.1.2", true},
+		{"<17.2", "17.2.0", false},
+		{">=120 <130", "129", true},
+		{">=120, <130", "130", false},
+		{"17.2", "17.2.0", true},
+		{"!=11", "10.15.7", true},
+		{">0", "Unknown", false},
+		{"!=1", "Unknown", false},
+	}
+	for _, data := range testData {
+		r, err := ParseVersionRange(data.versionRange)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual := r.Contains(MustParseVersion(data.version))
+		if actual != data.expected {
+			t.Errorf("Expected range '%s' containing '%s' to be %v",
+				data.versionRange, data.version, data.expected)
+		}
+	}
+
+	for _, invalid := range []string{"", ">=", "~1.2", ">=Unknown", "<1 >x"} {
+		if _, err := ParseVersionRange(invalid); err == nil {
+			t.Errorf("Expected '%s' not to be a valid version range", invalid)
+		}
+	}
+}
+
+// Test the browser and platform helpers against the example evidence used
+// by the on-premise examples.
+func TestBrowserAndPlatformHelpers(t *testing.T) {
+	filePath := testutil.DataFilePathOrSkip(t)
+	engine, err := onpremise.New(
+		onpremise.WithDataFile(filePath),
+		onpremise.WithAutoUpdate(false),
+		onpremise.WithFileWatch(false),
+		onpremise.WithLogging(false))
+	if err != nil {
+		t.Fatalf("Failed to create engine. %v", err)
+	}
+	defer engine.Stop()
+
+	testData := []struct {
+		name     string
+		evidence []onpremise.Evidence
+		check    func(d *DeviceResults) (bool, error)
+		expected bool
+	}{
+		{"Chrome >= 120", common.ExampleEvidence1, func(d *DeviceResults) (bool, error) {
+			return d.BrowserAtLeast("Chrome", "120")
+		}, true},
+		{"chrome >= 124", common.ExampleEvidence2, func(d *DeviceResults) (bool, error) {
+			return d.BrowserAtLeast("chrome", "124")
+		}, true},
+		{"Chrome >= 125", common.ExampleEvidence1, func(d *DeviceResults) (bool, error) {
+			return d.BrowserAtLeast("Chrome", "125")
+		}, false},
+		{"Chrome 120 to 129", common.ExampleEvidence1, func(d *DeviceResults) (bool, error) {
+			return d.BrowserMatches("Chrome", ">=120 <130")
+		}, true},
+		{"Firefox >= 1", common.ExampleEvidence1, func(d *DeviceResults) (bool, error) {
+			return d.BrowserAtLeast("Firefox", "1")
+		}, false},
+		{"macOS >= 10", common.ExampleEvidence1, func(d *DeviceResults) (bool, error) {
+			return d.PlatformAtLeast("macOS", "10")
+		}, true},
+		{"iOS < 17.2", common.ExampleEvidence1, func(d *DeviceResults) (bool, error) {
+			return d.PlatformMatches("iOS", "<17.2")
+		}, false},
+	}
+	for _, data := range testData {
+		results, err := engine.Process(data.evidence)
+		if err != nil {
+			t.Fatalf("Failed to process evidence. %v", err)
+		}
+		actual, err := data.check(NewDeviceResults(results))
+		results.Free()
+		if err != nil {
+			t.Errorf("%s: %v", data.name, err)
+		} else if actual != data.expected {
+			t.Errorf("%s: expected %v, but got %v", data.name, data.expected, actual)
+		}
+	}
+}
@@ -59,21 +59,32 @@ var ExampleEvidence2 = []onpremise.Evidence{
 }
 
 func ConvertToEvidence(values map[string]string) []onpremise.Evidence {
-	evidence := make([]onpremise.Evidence, len(values))
+	evidence := make([]onpremise.Evidence, 0, len(values))
 	for k, v := range values {
-		strSplit := strings.SplitN(k, ".", 2)
+		prefixStr, key, found := strings.Cut(k, ".")
+		if !found {
+			// Keys without a prefix are not evidence
+			continue
+		}
 		var prefix dd.EvidencePrefix
-		switch strSplit[0] {
-		case "header":
-			prefix = dd.HttpHeaderString
+		switch prefixStr {
+		case "meta":
+			// Information about the record rather than evidence
+			continue
 		case "query":
 			prefix = dd.HttpEvidenceQuery
+		case "cookie":
+			prefix = dd.HttpEvidenceCookie
+		case "server":
+			prefix = dd.HttpEvidenceServer
+		default:
+			prefix = dd.HttpHeaderString
 		}
 
 		evidence = append(evidence,
 			onpremise.Evidence{
 				Prefix: prefix,
-				Key:    strSplit[1],
+				Key:    key,
 				Value:  v,
 			})
 	}
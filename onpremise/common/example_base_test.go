@@ -0,0 +1,93 @@
+/* *********************************************************************
+ * This Original Work is copyright of 51 Degrees Mobile Experts Limited.
+ * Copyright 2019 51 Degrees Mobile Experts Limited, 5 Charlotte Close,
+ * Caversham, Reading, Berkshire, United Kingdom RG4 7BY.
+ *
+ * This Original Work is licensed under the European Union Public Licence (EUPL)
+ * v.1.2 and is subject to its terms as set out below.
+ *
+ * If a copy of the EUPL was not distributed with this file, You can obtain
+ * one at https://opensource.org/licenses/EUPL-1.2.
+ *
+ * The 'Compatible Licences' set out in the Appendix to the EUPL (as may be
+ * amended by the European Commission) shall be deemed incompatible for
+ * the purposes of the Work and the provisions of the compatibility
+ * clause in Article 5 of the EUPL shall not apply.
+ *
+ * If using the Work as, or as part of, a network application, by
+ * including the attribution notice(s) required under Article 5 of the EUPL
+ * in the end user terms of the application under an appropriate heading,
+ * such notice(s) shall fulfill the requirements of that article.
+ * ********************************************************************* */
+
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/51Degrees/device-detection-go/v4/dd"
+)
+
+// prefixes maps the prefixes of Evidence Records entries to the evidence
+// prefixes expected from ConvertToEvidence. Others are treated as headers.
+var prefixes = map[string]dd.EvidencePrefix{
+	"header": dd.HttpHeaderString,
+	"query":  dd.HttpEvidenceQuery,
+	"cookie": dd.HttpEvidenceCookie,
+	"server": dd.HttpEvidenceServer,
+}
+
+// Fuzz the conversion of Evidence Records entries with a key and value added
+// to a record. Only the meta entries and keys without a prefix are dropped, and
+// the others keep their prefix, key and value.
+func FuzzConvertToEvidence(f *testing.F) {
+	for _, e := range append(ExampleEvidence1, ExampleEvidence2...) {
+		f.Add("header."+e.Key, e.Value)
+	}
+	f.Add("query.user-agent", ExampleEvidence2[0].Value)
+	f.Add("cookie.51D_DeviceId", "12280-48866-24384-18092")
+	f.Add("server.client-ip", "192.0.2.1")
+	f.Add("unknown.key", "value")
+	f.Add("user-agent", "value")
+	f.Add("meta.name", "")
+	f.Add(".", "")
+
+	f.Fuzz(func(t *testing.T, key, value string) {
+		record := map[string]string{
+			"header.user-agent": ExampleEvidence2[0].Value,
+			"meta.name":         "fuzz",
+			key:                 value,
+		}
+		expected := make(map[string]dd.EvidencePrefix)
+		for k := range record {
+			prefix, _, found := strings.Cut(k, ".")
+			if !found || prefix == "meta" {
+				continue
+			}
+			if p, ok := prefixes[prefix]; ok {
+				expected[k] = p
+			} else {
+				expected[k] = dd.HttpHeaderString
+			}
+		}
+
+		evidence := ConvertToEvidence(record)
+		if len(evidence) != len(expected) {
+			t.Fatalf("Expected %d evidence for %v, but got %d",
+				len(expected), record, len(evidence))
+		}
+		for _, e := range evidence {
+			found := false
+			for k, p := range expected {
+				_, key, _ := strings.Cut(k, ".")
+				found = found ||
+					(p == e.Prefix && key == e.Key && record[k] == e.Value)
+			}
+			if !found {
+				t.Errorf("Evidence '%v: %s: %s' is not in %v",
+					e.Prefix, e.Key, e.Value, record)
+			}
+		}
+	})
+}
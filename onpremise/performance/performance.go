@@ -25,12 +25,9 @@ package main
 /*
 This example illustrates the performance of 51Degrees device detection solution.
 
-Expected output is as described at the "// Output:..." section locate at the
-bottom of this example.
-
 To run this example, perform the following command:
 ```
-go test -run Example_performance
+go run performance.go
 ```
 
 This example will output a report to ./performance_report.log. The report
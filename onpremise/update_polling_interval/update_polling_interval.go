@@ -4,6 +4,7 @@ import (
 	"log"
 	"time"
 
+	dd_example "github.com/51Degrees/device-detection-examples-go/v4/dd"
 	"github.com/51Degrees/device-detection-examples-go/v4/onpremise/common"
 	"github.com/51Degrees/device-detection-go/v4/onpremise"
 )
@@ -13,13 +14,13 @@ func processExampleEvidence(engine *onpremise.Engine, evidence []onpremise.Evide
 	resultsHash, _ := engine.Process(evidence)
 	defer resultsHash.Free()
 	//Get values from results
-	vendor, _ := resultsHash.ValuesString("HardwareVendor", ",")
-	name, _ := resultsHash.ValuesString("HardwareName", ",")
-	model, _ := resultsHash.ValuesString("HardwareModel", ",")
-	deviceType, _ := resultsHash.ValuesString("DeviceType", ",")
-	browser, _ := resultsHash.ValuesString("BrowserName", ",")
-	platform, _ := resultsHash.ValuesString("PlatformName", ",")
-	platformVersion, _ := resultsHash.ValuesString("PlatformVersion", ",")
+	vendor, _ := resultsHash.ValuesString(dd_example.PropertyHardwareVendor, ",")
+	name, _ := resultsHash.ValuesString(dd_example.PropertyHardwareName, ",")
+	model, _ := resultsHash.ValuesString(dd_example.PropertyHardwareModel, ",")
+	deviceType, _ := resultsHash.ValuesString(dd_example.PropertyDeviceType, ",")
+	browser, _ := resultsHash.ValuesString(dd_example.PropertyBrowserName, ",")
+	platform, _ := resultsHash.ValuesString(dd_example.PropertyPlatformName, ",")
+	platformVersion, _ := resultsHash.ValuesString(dd_example.PropertyPlatformVersion, ",")
 
 	log.Printf("HardwareVendor: %s", vendor)
 	log.Printf("HardwareName: %s", name)
@@ -55,15 +55,47 @@ package main
  You should see the html text returned with `Platform Name` set to `Windows`, and
  `Platform Version` set to `11.0`.
 
+ Evidence is extracted according to the prefix of each key required by the
+ engine: headers, query parameters, cookies and the client IP. When running
+ behind a reverse proxy, the headers it sets to carry the client IP can be
+ trusted by passing them to the `-trusted-proxy-headers` option:
+ ```
+ go run uach.go -trusted-proxy-headers "X-Forwarded-For,Forwarded"
+ ```
+ The client controls the addresses at the start of these headers, so the
+ rightmost address not added by a trusted proxy is used. If there is more than
+ one proxy in front of the server, their addresses are given by the
+ `-trusted-proxies` option. Otherwise a single proxy is assumed, which connects
+ to the server and appends the address of the client:
+ ```
+ go run uach.go -trusted-proxy-headers "X-Forwarded-For" -trusted-proxies "10.0.0.0/8"
+ ```
+
+ The evidence of each request can be captured to a file in the same format as
+ the Evidence Records file, to be replayed later by the offline processing and
+ performance examples:
+ ```
+ go run uach.go -capture-file capture.yml -capture-sample-rate 0.1
+ ```
+ The file is rotated to "capture.1.yml", "capture.2.yml" and so on once it
+ reaches the size given by `-capture-max-size`. Records already in the current
+ file are skipped unless `-capture-dedup=false` is given. Stop the server with
+ Ctrl+C, or SIGTERM, so that the file is finished.
+
 */
 
 import (
+	"flag"
 	"fmt"
 	"html/template"
 	"log"
+	"net"
 	"net/http"
+	"regexp"
 	"strings"
 
+	dd_example "github.com/51Degrees/device-detection-examples-go/v4/dd"
+
 	"github.com/51Degrees/device-detection-go/v4/dd"
 )
 
@@ -77,20 +109,23 @@ type stringEvidence struct {
 // Properties required for a response page.
 type Page struct {
 	Keys            []stringEvidence
-	HardwareVendor  string
-	HardwareName    string
-	DeviceType      string
-	PlatformVendor  string
-	PlatformName    string
-	PlatformVersion string
-	BrowserVendor   string
-	BrowserName     string
-	BrowserVersion  string
+	HardwareVendor  dd_example.PropertyValue
+	HardwareName    dd_example.PropertyValue
+	DeviceType      dd_example.PropertyValue
+	PlatformVendor  dd_example.PropertyValue
+	PlatformName    dd_example.PropertyValue
+	PlatformVersion dd_example.PropertyValue
+	BrowserVendor   dd_example.PropertyValue
+	BrowserName     dd_example.PropertyValue
+	BrowserVersion  dd_example.PropertyValue
 }
 
 var manager *dd.ResourceManager
 var config *dd.ConfigHash
 
+// Capture of request evidence. Nil unless enabled by the -capture-file option.
+var capture *dd_example.EvidenceCapture
+
 // Template for the response HTML page.
 
 var templ = `<!DOCTYPE HTML>
@@ -173,55 +208,157 @@ var templ = `<!DOCTYPE HTML>
 	   <div id=description></div>
 	   <div id="content">
 	      <strong>Detection results:</strong></br></br>
-	      <b>Hardware Vendor:</b> {{.HardwareVendor}}<br />
-	      <b>Hardware Name:</b> {{.HardwareName}}<br />
-	      <b>Device Type:</b> {{.DeviceType}}<br />
-	      <b>Platform Vendor:</b> {{.PlatformVendor}}<br />
-	      <b>Platform Name:</b> {{.PlatformName}}<br />
-	      <b>Platform Version:</b> {{.PlatformVersion}}<br />
-	      <b>Browser Vendor:</b> {{.BrowserVendor}}<br />
-	      <b>Browser Name:</b> {{.BrowserName}}<br />
-	      <b>Browser Version:</b> {{.BrowserVersion}}<br />
+	      <b>Hardware Vendor:</b> {{template "value" .HardwareVendor}}<br />
+	      <b>Hardware Name:</b> {{template "value" .HardwareName}}<br />
+	      <b>Device Type:</b> {{template "value" .DeviceType}}<br />
+	      <b>Platform Vendor:</b> {{template "value" .PlatformVendor}}<br />
+	      <b>Platform Name:</b> {{template "value" .PlatformName}}<br />
+	      <b>Platform Version:</b> {{template "value" .PlatformVersion}}<br />
+	      <b>Browser Vendor:</b> {{template "value" .BrowserVendor}}<br />
+	      <b>Browser Name:</b> {{template "value" .BrowserName}}<br />
+	      <b>Browser Version:</b> {{template "value" .BrowserVersion}}<br />
 	   </div>
    </body>
-</html>`
+</html>
+{{define "value"}}{{if .HasValue}}{{.Value}}{{else}}<i title="{{.Message}}">{{.Reason}}</i>{{end}}{{end}}`
 
 // Prefixes in literal format
 const queryPrefix = "query."
 const headerPrefix = "header."
+const cookiePrefix = "cookie."
+const serverPrefix = "server."
+
+// Headers set by trusted reverse proxies which carry the original client IP
+// address. The headers are checked in order and the first one containing a
+// valid address is used. If none is trusted, or none carries a valid address,
+// the remote address of the connection is used instead.
+var trustedProxyHeaders []string
+
+// Networks of the trusted reverse proxies. Addresses in these networks are
+// skipped when looking for the client in the trusted proxy headers. If there
+// are none, a single proxy is assumed.
+var trustedProxies []*net.IPNet
+
+// forwardedForPattern extracts the value of the 'for' parameter from an
+// element of a 'Forwarded' header as described in RFC 7239.
+var forwardedForPattern = regexp.MustCompile(`(?i)(?:^|;)\s*for=("[^"]*"|[^;,\s]*)`)
+
+// parseIP returns the IP address in a forwarded value with any quotes, IPv6
+// brackets and port removed. Returns an empty string if the value is not an IP
+// address (e.g. an obfuscated identifier or "unknown").
+func parseIP(value string) string {
+	value = strings.Trim(strings.TrimSpace(value), "\"")
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	if ip := net.ParseIP(value); ip != nil {
+		return ip.String()
+	}
+	return ""
+}
 
-func extractEvidenceStrings(r *http.Request, keys []dd.EvidenceKey) []stringEvidence {
-	evidence := make([]stringEvidence, 0)
-	for _, e := range keys {
-		lowerKey := strings.ToLower(e.Key)
-		switch e.Prefix {
-		case dd.HttpEvidenceQuery:
-			// Get evidence from query parameter
-			queryVal := r.URL.Query().Get(lowerKey)
-			if queryVal != "" {
-				evidence = append(
-					evidence, stringEvidence{queryPrefix, e.Key, queryVal})
+// isTrustedProxy returns true if the address is in a trusted proxy network.
+func isTrustedProxy(ip string) bool {
+	parsed := net.ParseIP(ip)
+	for _, n := range trustedProxies {
+		if parsed != nil && n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedAddresses returns the addresses of a trusted proxy header, in the
+// order they were added. Elements which are not IP addresses, such as
+// obfuscated identifiers, are returned as empty strings.
+func forwardedAddresses(header, value string) []string {
+	elements := strings.Split(value, ",")
+	addresses := make([]string, len(elements))
+	for i, e := range elements {
+		if strings.EqualFold(header, "Forwarded") {
+			if m := forwardedForPattern.FindStringSubmatch(e); m != nil {
+				addresses[i] = parseIP(m[1])
 			}
-		default:
-			// Get evidence from headers
-			headerVal := r.Header.Get(lowerKey)
-			if headerVal != "" {
-				evidence = append(
-					evidence, stringEvidence{headerPrefix, e.Key, headerVal})
+		} else {
+			addresses[i] = parseIP(e)
+		}
+	}
+	return addresses
+}
+
+// parseNetwork parses a network in CIDR notation, or a single address.
+func parseNetwork(value string) (*net.IPNet, error) {
+	if !strings.Contains(value, "/") {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, fmt.Errorf("not an IP address")
+		}
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, n, err := net.ParseCIDR(value)
+	return n, err
+}
+
+// clientIP derives the IP address of the client which made the request. The
+// trusted proxy headers are only consulted if the connection is from a trusted
+// proxy. As the client can send any addresses in these headers, they are read
+// from the right, skipping the addresses of trusted proxies, and the first
+// other address is the client. If an element cannot be read the next header is
+// tried, and the remote address of the connection is used if none identify the
+// client.
+func clientIP(r *http.Request) string {
+	remote := parseIP(r.RemoteAddr)
+	if len(trustedProxies) > 0 && !isTrustedProxy(remote) {
+		return remote
+	}
+	for _, header := range trustedProxyHeaders {
+		value := r.Header.Get(header)
+		if value == "" {
+			continue
+		}
+		addresses := forwardedAddresses(header, value)
+		for i := len(addresses) - 1; i >= 0; i-- {
+			if addresses[i] == "" {
+				break
+			}
+			if !isTrustedProxy(addresses[i]) || i == 0 {
+				return addresses[i]
 			}
 		}
 	}
+	return remote
+}
+
+// extractEvidenceStrings extracts the values of the required evidence keys
+// from a http request, as ExtractRequestEvidence does when capturing them.
+func extractEvidenceStrings(r *http.Request, keys []dd.EvidenceKey) []stringEvidence {
+	evidence := make([]stringEvidence, 0)
+	for _, e := range dd_example.ExtractRequestEvidence(r, keys, clientIP) {
+		evidence = append(evidence, stringEvidence{e.Prefix + ".", e.Key, e.Value})
+	}
 	return evidence
 }
 
 // extractEvidence looks into a list of required evidence keys and extract
 // them from a http request.
-func extractEvidence(strEvidence []stringEvidence) *dd.Evidence {
-	evidence := dd.NewEvidenceHash(uint32(len(strEvidence)))
+func extractEvidence(strEvidence []stringEvidence) *dd_example.TrackedEvidence {
+	evidence := dd_example.NewTrackedEvidence(uint32(len(strEvidence)))
 	for _, e := range strEvidence {
-		prefix := dd.HttpHeaderString
-		if e.Prefix == queryPrefix {
+		var prefix dd.EvidencePrefix
+		switch e.Prefix {
+		case queryPrefix:
 			prefix = dd.HttpEvidenceQuery
+		case cookiePrefix:
+			prefix = dd.HttpEvidenceCookie
+		case serverPrefix:
+			prefix = dd.HttpEvidenceServer
+		default:
+			prefix = dd.HttpHeaderString
 		}
 		evidence.Add(prefix, e.Key, e.Value)
 	}
@@ -235,53 +372,49 @@ func match(
 	evidence *dd.Evidence) {
 	err := results.MatchEvidence(evidence)
 	if err != nil {
-		log.Fatal("ERROR: Failed to perform detection.")
+		dd_example.Fatal("ERROR: Failed to perform detection.")
 	}
 }
 
-// function getValue return a value results for a property
+// function getValue return a value results for a property, or the reason
+// there is not one which is rendered in its place.
 func getValue(
 	results *dd.ResultsHash,
-	propertyName string) string {
-	// Get the values in string
-	value, err := results.ValuesString(
-		propertyName,
-		",")
-	if err != nil {
-		log.Fatalln("ERROR: Failed to get results values string.")
-	}
-
-	hasValues, err := results.HasValues(propertyName)
-	if err != nil {
-		log.Fatalf(
-			"ERROR: Failed to check if a matched value exists for property "+
-				"%s.\n", propertyName)
-	}
-
-	if !hasValues {
+	propertyName string) dd_example.PropertyValue {
+	value := dd_example.GetPropertyValue(results, propertyName)
+	switch value.Reason {
+	case dd_example.ValueError:
+		log.Printf("ERROR: Failed to get value for property %s. %v\n",
+			propertyName, value.Err)
+	case dd_example.ValueNoValue, dd_example.ValueNull:
 		log.Printf("Property %s does not have a matched value.\n", propertyName)
-		return "Unknown"
 	}
-
 	return value
 }
 
 // Handler for web request
 func handler(w http.ResponseWriter, r *http.Request) {
 	filteredEvidence := extractEvidenceStrings(r, manager.HttpHeaderKeys)
+	// Capture the evidence if enabled
+	if capture != nil {
+		err := capture.CaptureRequest(r, manager.HttpHeaderKeys, clientIP)
+		if err != nil {
+			log.Printf("ERROR: Failed to capture evidence. %v\n", err)
+		}
+	}
 	// Extract evidence
 	evidence := extractEvidence(filteredEvidence)
 	// Make sure evidence is freed at the end
 	defer evidence.Free()
 
 	// Create results
-	results := dd.NewResultsHash(manager, uint32(evidence.Count()), 0)
+	results := dd_example.NewTrackedResults(manager, uint32(evidence.Count()), 0)
 
 	// Make sure results object is freed after function execution.
 	defer results.Free()
 
 	// Perform detection on mobile User-Agent
-	match(results, evidence)
+	match(results.ResultsHash, evidence.Evidence)
 
 	// NOTE: Add response headers to request User-Agent Client Hints
 	// from client. This is IMPORTANT so that User-Agent Client Hints
@@ -289,15 +422,15 @@ func handler(w http.ResponseWriter, r *http.Request) {
 	// requests.
 	results.SetResponseHeaders(w, manager)
 
-	hardwareVendor := getValue(results, "HardwareVendor")
-	hardwareName := getValue(results, "HardwareName")
-	deviceType := getValue(results, "DeviceType")
-	platformVendor := getValue(results, "PlatformVendor")
-	platformName := getValue(results, "PlatformName")
-	platformVersion := getValue(results, "PlatformVersion")
-	browserVendor := getValue(results, "BrowserVendor")
-	browserName := getValue(results, "BrowserName")
-	browserVersion := getValue(results, "BrowserVersion")
+	hardwareVendor := getValue(results.ResultsHash, dd_example.PropertyHardwareVendor)
+	hardwareName := getValue(results.ResultsHash, dd_example.PropertyHardwareName)
+	deviceType := getValue(results.ResultsHash, dd_example.PropertyDeviceType)
+	platformVendor := getValue(results.ResultsHash, dd_example.PropertyPlatformVendor)
+	platformName := getValue(results.ResultsHash, dd_example.PropertyPlatformName)
+	platformVersion := getValue(results.ResultsHash, dd_example.PropertyPlatformVersion)
+	browserVendor := getValue(results.ResultsHash, dd_example.PropertyBrowserVendor)
+	browserName := getValue(results.ResultsHash, dd_example.PropertyBrowserName)
+	browserVersion := getValue(results.ResultsHash, dd_example.PropertyBrowserVersion)
 	p := &Page{
 		filteredEvidence,
 		hardwareVendor,
@@ -318,8 +451,41 @@ func handler(w http.ResponseWriter, r *http.Request) {
 }
 
 func main() {
+	defer dd_example.ReportLeaksAtExit()
+
+	// Headers of trusted reverse proxies used to find the client IP
+	proxyHeaders := flag.String(
+		"trusted-proxy-headers",
+		"",
+		"Comma separated list of headers set by trusted proxies which carry "+
+			"the client IP (e.g. \"X-Forwarded-For,Forwarded\")")
+	proxies := flag.String(
+		"trusted-proxies",
+		"",
+		"Comma separated list of the addresses or networks of trusted "+
+			"proxies (e.g. \"10.0.0.0/8,192.0.2.1\"). A single proxy is "+
+			"assumed if none are given")
+	captureOptions := dd_example.CaptureFlags()
+	flag.Parse()
+	for _, h := range strings.Split(*proxyHeaders, ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			trustedProxyHeaders = append(trustedProxyHeaders, h)
+		}
+	}
+	for _, p := range strings.Split(*proxies, ",") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		n, err := parseNetwork(p)
+		if err != nil {
+			dd_example.Fatalf("ERROR: Invalid trusted proxy \"%s\". %v\n", p, err)
+		}
+		trustedProxies = append(trustedProxies, n)
+	}
+
 	// Initialise manager
-	manager = dd.NewResourceManager()
+	tracked := dd_example.NewTrackedManager()
+	manager = tracked.ResourceManager
 	config = dd.NewConfigHash(dd.Balanced)
 	config.SetUseUpperPrefixHeaders(false)
 	fileNames := []string{"51Degrees-LiteV4.1.hash"}
@@ -327,7 +493,7 @@ func main() {
 		"..",
 		fileNames)
 	if err != nil {
-		log.Fatalf("Could not find any file that matches any of \"%s\".\n",
+		dd_example.Fatalf("Could not find any file that matches any of \"%s\".\n",
 			strings.Join(fileNames, ", "))
 	}
 	// Init manager
@@ -337,14 +503,30 @@ func main() {
 		"",
 		filePath)
 	if err != nil {
-		log.Fatalln("ERROR: Failed to initialize resource manager.")
+		dd_example.Fatalln("ERROR: Failed to initialize resource manager.")
 	}
 
 	// Make sure manager object will be freed after the function execution
-	defer manager.Free()
+	defer tracked.Free()
+
+	// Start capturing evidence if enabled
+	capture, err = dd_example.NewEvidenceCapture(*captureOptions)
+	if err != nil {
+		dd_example.Fatalf("ERROR: Failed to start evidence capture. %v\n", err)
+	}
 
 	http.HandleFunc("/", handler)
 	const port = 3001
 	fmt.Printf("Server listening on port: %d\n", port)
-	log.Fatal(http.ListenAndServe(fmt.Sprintf("localhost:%d", port), nil))
+	err = dd_example.ListenAndServeUntilSignal(fmt.Sprintf("localhost:%d", port), nil)
+
+	// Finish the capture file once no more requests are being handled
+	if capture != nil {
+		if err := capture.Close(); err != nil {
+			log.Printf("ERROR: Failed to finish evidence capture. %v\n", err)
+		}
+	}
+	if err != nil {
+		dd_example.Fatal(err)
+	}
 }
@@ -33,9 +33,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"reflect"
 	"strings"
 	"testing"
 
+	dd_example "github.com/51Degrees/device-detection-examples-go/v4/dd"
+
 	"github.com/51Degrees/device-detection-go/v4/dd"
 )
 
@@ -76,37 +79,50 @@ func TestExtractEvidence(t *testing.T) {
 		value  string
 	}
 
+	userAgent := dd.EvidenceKey{Prefix: dd.HttpHeaderString, Key: "User-Agent"}
+	queryParam := dd.EvidenceKey{Prefix: dd.HttpEvidenceQuery, Key: "Query-Param"}
+	cookie := dd.EvidenceKey{Prefix: dd.HttpEvidenceCookie, Key: "Cookie-Name"}
+	clientIp := dd.EvidenceKey{Prefix: dd.HttpEvidenceServer, Key: "client-ip"}
+
 	testData := []struct {
-		keys          []dd.EvidenceKey
-		evidence      []evidenceStruct
-		expectedCount int
+		keys           []dd.EvidenceKey
+		evidence       []evidenceStruct
+		remoteAddr     string
+		trustedHeaders []string
+		expected       []stringEvidence
 	}{
 		{
-			[]dd.EvidenceKey{{dd.HttpHeaderString, "User-Agent"}},
+			[]dd.EvidenceKey{userAgent},
 			[]evidenceStruct{
 				{dd.HttpHeaderString, "User-Agent", "TestUserAgent"},
 				{dd.HttpEvidenceQuery, "query-param", "TestQueryParam"},
 			},
-			1,
+			"",
+			nil,
+			[]stringEvidence{{headerPrefix, "User-Agent", "TestUserAgent"}},
 		},
 		{
-			[]dd.EvidenceKey{{dd.HttpEvidenceQuery, "Query-Param"}},
+			[]dd.EvidenceKey{queryParam},
 			[]evidenceStruct{
 				{dd.HttpHeaderString, "User-Agent", "TestUserAgent"},
 				{dd.HttpEvidenceQuery, "query-param", "TestQueryParam"},
 			},
-			1,
+			"",
+			nil,
+			[]stringEvidence{{queryPrefix, "Query-Param", "TestQueryParam"}},
 		},
 		{
-			[]dd.EvidenceKey{
-				{dd.HttpHeaderString, "User-Agent"},
-				{dd.HttpEvidenceQuery, "Query-Param"},
-			},
+			[]dd.EvidenceKey{userAgent, queryParam},
 			[]evidenceStruct{
 				{dd.HttpHeaderString, "User-Agent", "TestUserAgent"},
 				{dd.HttpEvidenceQuery, "query-param", "TestQueryParam"},
 			},
-			2,
+			"",
+			nil,
+			[]stringEvidence{
+				{headerPrefix, "User-Agent", "TestUserAgent"},
+				{queryPrefix, "Query-Param", "TestQueryParam"},
+			},
 		},
 		{
 			make([]dd.EvidenceKey, 0),
@@ -114,7 +130,71 @@ func TestExtractEvidence(t *testing.T) {
 				{dd.HttpHeaderString, "User-Agent", "TestUserAgent"},
 				{dd.HttpEvidenceQuery, "query-param", "TestQueryParam"},
 			},
-			0,
+			"",
+			nil,
+			[]stringEvidence{},
+		},
+		// Cookies are read from the request cookies, not from headers of
+		// the same name.
+		{
+			[]dd.EvidenceKey{cookie},
+			[]evidenceStruct{
+				{dd.HttpHeaderString, "Cookie-Name", "TestHeader"},
+				{dd.HttpEvidenceCookie, "cookie-name", "TestCookie"},
+			},
+			"",
+			nil,
+			[]stringEvidence{{cookiePrefix, "Cookie-Name", "TestCookie"}},
+		},
+		{
+			[]dd.EvidenceKey{userAgent, cookie},
+			[]evidenceStruct{
+				{dd.HttpHeaderString, "User-Agent", "TestUserAgent"},
+			},
+			"",
+			nil,
+			[]stringEvidence{{headerPrefix, "User-Agent", "TestUserAgent"}},
+		},
+		// Client IP is taken from the remote address when no proxy is
+		// trusted.
+		{
+			[]dd.EvidenceKey{clientIp},
+			[]evidenceStruct{
+				{dd.HttpHeaderString, "X-Forwarded-For", "203.0.113.7"},
+			},
+			"192.0.2.1:51234",
+			nil,
+			[]stringEvidence{{serverPrefix, "client-ip", "192.0.2.1"}},
+		},
+		{
+			[]dd.EvidenceKey{clientIp},
+			[]evidenceStruct{
+				{dd.HttpHeaderString, "X-Forwarded-For", "198.51.100.2, 203.0.113.7"},
+			},
+			"192.0.2.1:51234",
+			[]string{"X-Forwarded-For"},
+			[]stringEvidence{{serverPrefix, "client-ip", "203.0.113.7"}},
+		},
+		{
+			[]dd.EvidenceKey{userAgent, clientIp},
+			[]evidenceStruct{
+				{dd.HttpHeaderString, "User-Agent", "TestUserAgent"},
+				{dd.HttpHeaderString, "Forwarded", "for=10.0.0.1, for=\"[2001:db8:cafe::17]:4711\";proto=https"},
+			},
+			"192.0.2.1:51234",
+			[]string{"X-Forwarded-For", "Forwarded"},
+			[]stringEvidence{
+				{headerPrefix, "User-Agent", "TestUserAgent"},
+				{serverPrefix, "client-ip", "2001:db8:cafe::17"},
+			},
+		},
+		// Other server values cannot be derived from the request.
+		{
+			[]dd.EvidenceKey{{Prefix: dd.HttpEvidenceServer, Key: "host"}},
+			[]evidenceStruct{},
+			"192.0.2.1:51234",
+			nil,
+			[]stringEvidence{},
 		},
 	}
 
@@ -122,22 +202,85 @@ func TestExtractEvidence(t *testing.T) {
 		request := new(http.Request)
 		request.Header = make(http.Header)
 		request.URL = &url.URL{}
+		request.RemoteAddr = data.remoteAddr
 		for _, item := range data.evidence {
 			switch item.prefix {
 			case dd.HttpEvidenceQuery:
 				request.URL.RawQuery = fmt.Sprintf("%s=%s", item.key, item.value)
+			case dd.HttpEvidenceCookie:
+				request.AddCookie(&http.Cookie{Name: item.key, Value: item.value})
 			default:
 				request.Header.Set(item.key, item.value)
 			}
 		}
+		trustedProxyHeaders = data.trustedHeaders
 
 		strEvidence := extractEvidenceStrings(request, data.keys)
+		if !reflect.DeepEqual(strEvidence, data.expected) {
+			t.Errorf("Expected evidence '%v', but got '%v'",
+				data.expected, strEvidence)
+		}
+
 		evidence := extractEvidence(strEvidence)
 		count := evidence.Count()
 		evidence.Free()
-		if count != data.expectedCount {
+		if count != len(data.expected) {
 			t.Errorf("Expected '%d' evidence, but got '%d'",
-				data.expectedCount, count)
+				len(data.expected), count)
+		}
+	}
+	trustedProxyHeaders = nil
+}
+
+// Test that the client IP is derived from each supported proxy header
+// format and falls back to the remote address. Addresses the client can spoof,
+// at the start of the headers, are not used.
+func TestClientIP(t *testing.T) {
+	testData := []struct {
+		header     string
+		value      string
+		remoteAddr string
+		proxies    []string
+		expected   string
+	}{
+		{"X-Forwarded-For", "203.0.113.7", "192.0.2.1:80", nil, "203.0.113.7"},
+		{"X-Forwarded-For", " 203.0.113.7 , 198.51.100.2", "192.0.2.1:80", nil, "198.51.100.2"},
+		{"X-Forwarded-For", "unknown", "192.0.2.1:80", nil, "192.0.2.1"},
+		{"Forwarded", "for=192.0.2.60;proto=http;by=203.0.113.43", "192.0.2.1:80", nil, "192.0.2.60"},
+		{"Forwarded", "proto=http; For=\"198.51.100.17:8080\"", "192.0.2.1:80", nil, "198.51.100.17"},
+		{"Forwarded", "for=198.51.100.17, for=_hidden", "192.0.2.1:80", nil, "192.0.2.1"},
+		{"", "", "[2001:db8::1]:443", nil, "2001:db8::1"},
+		{"", "", "", nil, ""},
+		// Addresses of trusted proxies are skipped from the right
+		{"X-Forwarded-For", "6.6.6.6, 203.0.113.7, 10.0.0.2", "10.0.0.1:80", []string{"10.0.0.0/8"}, "203.0.113.7"},
+		{"X-Forwarded-For", "10.0.0.3, 10.0.0.2", "10.0.0.1:80", []string{"10.0.0.0/8"}, "10.0.0.3"},
+		// Headers are ignored if the connection is not from a trusted proxy
+		{"X-Forwarded-For", "203.0.113.7", "192.0.2.1:80", []string{"10.0.0.1"}, "192.0.2.1"},
+	}
+
+	trustedProxyHeaders = []string{"X-Forwarded-For", "Forwarded"}
+	defer func() {
+		trustedProxyHeaders = nil
+		trustedProxies = nil
+	}()
+	for _, data := range testData {
+		trustedProxies = nil
+		for _, p := range data.proxies {
+			n, err := parseNetwork(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			trustedProxies = append(trustedProxies, n)
+		}
+		request := new(http.Request)
+		request.Header = make(http.Header)
+		request.RemoteAddr = data.remoteAddr
+		if data.header != "" {
+			request.Header.Set(data.header, data.value)
+		}
+		if ip := clientIP(request); ip != data.expected {
+			t.Errorf("Expected client IP '%s' for %s '%s', but got '%s'",
+				data.expected, data.header, data.value, ip)
 		}
 	}
 }
@@ -326,3 +469,70 @@ func TestHandler(t *testing.T) {
 		}
 	}
 }
+
+// Fuzz the extraction of evidence from a request carrying the value given as
+// a header, query parameter and cookie of the name given. Keys must be
+// returned as requested, with the prefix of the evidence key, and query
+// parameters must be found whatever the case of their name.
+func FuzzExtractEvidenceStrings(f *testing.F) {
+	for _, ua := range []string{chromeUA, edgeUA, firefoxUA, curlUA, safariUA} {
+		f.Add("User-Agent", ua)
+	}
+	f.Add("Sec-CH-UA", "\"Chromium\";v=\"95\", \"Google Chrome\";v=\"95\"")
+	f.Add("Sec-CH-UA-Mobile", "?0")
+	f.Add("client-ip", "192.0.2.1")
+	f.Add("", "")
+	f.Add("a=b&c", "d;e")
+
+	prefixes := map[dd.EvidencePrefix]string{
+		dd.HttpHeaderString:   headerPrefix,
+		dd.HttpEvidenceQuery:  queryPrefix,
+		dd.HttpEvidenceCookie: cookiePrefix,
+		dd.HttpEvidenceServer: serverPrefix,
+	}
+
+	f.Fuzz(func(t *testing.T, name, value string) {
+		request := new(http.Request)
+		request.Header = make(http.Header)
+		request.Header.Set(name, value)
+		request.URL = &url.URL{RawQuery: url.Values{name: {value}}.Encode()}
+		request.RemoteAddr = "192.0.2.1:51234"
+		request.AddCookie(&http.Cookie{Name: name, Value: value})
+
+		keys := []dd.EvidenceKey{
+			{Prefix: dd.HttpHeaderString, Key: name},
+			{Prefix: dd.HttpEvidenceQuery, Key: name},
+			{Prefix: dd.HttpEvidenceCookie, Key: name},
+			{Prefix: dd.HttpEvidenceServer, Key: name},
+		}
+		strEvidence := extractEvidenceStrings(request, keys)
+		if len(strEvidence) > len(keys) {
+			t.Fatalf("Expected at most %d evidence, but got %v",
+				len(keys), strEvidence)
+		}
+		i := 0
+		for _, e := range strEvidence {
+			for i < len(keys) && prefixes[keys[i].Prefix] != e.Prefix {
+				i++
+			}
+			if i == len(keys) {
+				t.Fatalf("Evidence %v is not in the order of the keys %v",
+					strEvidence, keys)
+			}
+			if e.Key != name {
+				t.Errorf("Expected key '%s', but got '%s'", name, e.Key)
+			}
+			i++
+		}
+		if value != "" && dd_example.QueryValue(request, name) != value {
+			t.Errorf("Expected query parameter '%s' to be '%s'", name, value)
+		}
+
+		evidence := extractEvidence(strEvidence)
+		defer evidence.Free()
+		if evidence.Count() != len(strEvidence) {
+			t.Errorf("Expected %d extracted evidence, but got %d",
+				len(strEvidence), evidence.Count())
+		}
+	})
+}
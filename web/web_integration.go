@@ -43,27 +43,65 @@ used:
 ```
 curl -A [User-Agent string] localhost:8000
 ```
+
+The evidence of each request can be captured to a file in the same format as
+the Evidence Records file by passing `-capture-file`. See `go run
+web_integration.go -h` for the sampling, rotation and deduplication options.
+Stop the server with Ctrl+C, or SIGTERM, so that the file is finished.
+
+Returning visitors can be resolved from the device ID of their previous
+detection rather than a full detection by passing `-device-id-key`. The device
+ID is stored in a signed cookie which is only trusted for the same User-Agent
+and for `-device-id-max-age`. A token is only issued for detections with a
+Drift and Difference within `-device-id-max-drift` and
+`-device-id-max-difference`. Metrics comparing the two paths are served at
+"localhost:8000/device-id/metrics".
+
+The parts of the User-Agent which were matched are shown at
+"localhost:8000/matched", or for any User-Agent at
+"localhost:8000/matched?ua=[User-Agent string]".
 */
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
 	"strings"
+	"time"
+
+	dd_example "github.com/51Degrees/device-detection-examples-go/v4/dd"
 
 	"github.com/51Degrees/device-detection-go/v4/dd"
 )
 
 // Properties required for a response page.
 type Page struct {
-	BrowserName       string
-	ScreenPixelsWidth string
+	BrowserName       dd_example.PropertyValue
+	ScreenPixelsWidth dd_example.PropertyValue
 }
 
 var manager *dd.ResourceManager
 var config *dd.ConfigHash
 
+// Capture of request evidence. Nil unless enabled by the -capture-file option.
+var capture *dd_example.EvidenceCapture
+
+// Device id tokens of returning visitors. Nil unless enabled by the
+// -device-id-key option.
+var tokens *dd_example.DeviceIdTokens
+
+// Name of the cookie holding a device id token
+const deviceIdCookie = "51D_DeviceId"
+
+// Properties shown on the response page
+var pageProperties = []string{
+	dd_example.PropertyBrowserName,
+	dd_example.PropertyScreenPixelsWidth,
+}
+
 // Template for the response HTML page.
 var templ = `<!DOCTYPE HTML>
 <html>
@@ -72,11 +110,41 @@ var templ = `<!DOCTYPE HTML>
     <title>Web Integration Example</title>
   </head>
   <body>
-    <p id=browsername>Browser: <b>{{.BrowserName}}</b></p>
-    <p id=screenpixelswidth>Screen Pixels Width: <b>{{.ScreenPixelsWidth}}</b></p>
+    <p id=browsername>Browser: <b>{{template "value" .BrowserName}}</b></p>
+    <p id=screenpixelswidth>Screen Pixels Width: <b>{{template "value" .ScreenPixelsWidth}}</b></p>
+  </body>
+</html>
+{{define "value"}}{{if .HasValue}}{{.Value}}{{else}}<i title="{{.Message}}">{{.Reason}}</i>{{end}}{{end}}`
+
+// Template for the matched User-Agent page.
+var matchedTempl = `<!DOCTYPE HTML>
+<html>
+  <head>
+    <meta charset="utf-8">
+    <title>Matched User-Agent</title>
+    <style>
+      .matched { background: #c8f0c8; }
+      .unmatched { background: #f0c8c8; }
+    </style>
+  </head>
+  <body>
+    <p id=useragent><code>{{.UserAgent}}</code></p>
+    <p id=matchedshare>Matched: <b>{{printf "%.1f" .Share}}%</b></p>
+    <p id=method>Method: <b>{{.Method}}</b></p>
+    <p id=drift>Drift: <b>{{.Drift}}</b></p>
+    <p id=difference>Difference: <b>{{.Difference}}</b></p>
   </body>
 </html>`
 
+// Properties of the matched User-Agent page.
+type MatchedPage struct {
+	UserAgent  template.HTML
+	Share      float64
+	Method     string
+	Drift      int32
+	Difference int32
+}
+
 // function match performs a match on an input User-Agent string and determine
 // if the device is a mobile device.
 func match(
@@ -85,49 +153,156 @@ func match(
 	// Perform detection
 	err := results.MatchUserAgent(ua)
 	if err != nil {
-		log.Fatalf("ERROR: Failed to perform detection on User-Agent \"%s\".\n", ua)
+		dd_example.Fatalf("ERROR: Failed to perform detection on User-Agent \"%s\".\n", ua)
 	}
 }
 
-// function getValue return a value results for a property
+// function getValue return a value results for a property, or the reason
+// there is not one which is rendered in its place.
 func getValue(
 	results *dd.ResultsHash,
-	propertyName string) string {
-	// Get the values in string
-	value, err := results.ValuesString(
-		propertyName,
-		",")
-	if err != nil {
-		log.Fatalln("ERROR: Failed to get results values string.")
+	propertyName string) dd_example.PropertyValue {
+	value := dd_example.GetPropertyValue(results, propertyName)
+	switch value.Reason {
+	case dd_example.ValueError:
+		log.Printf("ERROR: Failed to get value for property %s. %v\n",
+			propertyName, value.Err)
+	case dd_example.ValueNoValue, dd_example.ValueNull:
+		log.Printf("Property %s does not have a matched value.\n", propertyName)
+	}
+	return value
+}
+
+// function detect performs a detection for the request. If device id tokens
+// are enabled, a valid token from the request is used instead of the
+// User-Agent, and a token is issued for a trusted detection otherwise.
+func detect(
+	w http.ResponseWriter,
+	r *http.Request,
+	results *dd.ResultsHash) {
+	ua := r.UserAgent()
+	if tokens == nil {
+		match(results, ua)
+		return
 	}
 
-	hasValues, err := results.HasValues(propertyName)
+	if cookie, err := r.Cookie(deviceIdCookie); err == nil {
+		deviceId, err := tokens.Verify(cookie.Value, ua)
+		if err == nil {
+			start := time.Now()
+			err = results.MatchDeviceId(deviceId)
+			if err == nil {
+				tokens.ObserveDetection(true, time.Since(start))
+				if tokens.ShouldCompare() {
+					tokens.ObserveComparison(sameAsEvidence(results, ua))
+				}
+				return
+			}
+		}
+		log.Printf("Device id token not used. %v\n", err)
+	}
+
+	start := time.Now()
+	match(results, ua)
+	tokens.ObserveDetection(false, time.Since(start))
+	deviceId, err := results.DeviceId()
 	if err != nil {
-		log.Fatalf(
-			"ERROR: Failed to check if a matched value exists for property "+
-				"%s.\n", propertyName)
+		log.Printf("ERROR: Failed to get device id. %v\n", err)
+		return
+	}
+	if token, ok := tokens.Issue(
+		deviceId, ua, dd_example.QualityOf(results)); ok {
+		http.SetCookie(w, &http.Cookie{
+			Name:     deviceIdCookie,
+			Value:    token,
+			Path:     "/",
+			MaxAge:   int(tokens.Rules.MaxAge.Seconds()),
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+}
+
+// function sameAsEvidence performs a detection on the User-Agent and returns
+// true if the values of the page properties are the same as in the results.
+// Properties without a value in either results are skipped without logging,
+// as they are logged when the page is rendered.
+func sameAsEvidence(results *dd.ResultsHash, ua string) bool {
+	uaResults := dd_example.NewTrackedResults(manager, 1, 0)
+	defer uaResults.Free()
+	match(uaResults.ResultsHash, ua)
+	for _, property := range pageProperties {
+		value := dd_example.GetPropertyValue(results, property)
+		uaValue := dd_example.GetPropertyValue(uaResults.ResultsHash, property)
+		if !value.HasValue() && !uaValue.HasValue() {
+			continue
+		}
+		if value.String() != uaValue.String() {
+			return false
+		}
 	}
+	return true
+}
 
-	if !hasValues {
-		log.Printf("Property %s does not have a matched value.\n", propertyName)
-		return ""
+// Handler showing which parts of the User-Agent were matched. The User-Agent
+// of the request is used unless one is given in the 'ua' query parameter.
+func matchedHandler(w http.ResponseWriter, r *http.Request) {
+	ua := r.URL.Query().Get("ua")
+	if ua == "" {
+		ua = r.UserAgent()
 	}
 
-	return value
+	results := dd_example.NewTrackedResults(manager, 1, 0)
+	defer results.Free()
+	match(results.ResultsHash, ua)
+
+	// We only use one User-Agent so there can only be one result
+	matched, err := results.UserAgent(0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	segments := dd_example.AlignMatchedUserAgent(ua, matched)
+	p := &MatchedPage{
+		// The segments are escaped by the renderer
+		template.HTML(dd_example.RenderMatchHTML(segments)),
+		dd_example.MatchedShare(segments) * 100,
+		dd_example.MatchMethodName(results.Method()),
+		results.Drift(),
+		results.Difference(),
+	}
+	t := template.Must(template.New("matched").Parse(matchedTempl))
+	t.Execute(w, p)
+}
+
+// Handler for the metrics of device id tokens
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(tokens.Metrics()); err != nil {
+		log.Printf("ERROR: Failed to write metrics. %v\n", err)
+	}
 }
 
 // Handler for web request
 func handler(w http.ResponseWriter, r *http.Request) {
+	// Capture the evidence if enabled
+	if capture != nil {
+		err := capture.CaptureRequest(r, manager.HttpHeaderKeys, nil)
+		if err != nil {
+			log.Printf("ERROR: Failed to capture evidence. %v\n", err)
+		}
+	}
+
 	// Create results
-	results := dd.NewResultsHash(manager, 1, 0)
+	results := dd_example.NewTrackedResults(manager, 1, 0)
 
 	// Make sure results object is freed after function execution.
 	defer results.Free()
 
 	// Perform detection on mobile User-Agent
-	match(results, r.UserAgent())
-	browserName := getValue(results, "BrowserName")
-	screenPixelWidth := getValue(results, "ScreenPixelsWidth")
+	detect(w, r, results.ResultsHash)
+	browserName := getValue(results.ResultsHash, dd_example.PropertyBrowserName)
+	screenPixelWidth := getValue(results.ResultsHash, dd_example.PropertyScreenPixelsWidth)
 	p := &Page{
 		browserName,
 		screenPixelWidth,
@@ -140,15 +315,53 @@ func handler(w http.ResponseWriter, r *http.Request) {
 }
 
 func main() {
+	defer dd_example.ReportLeaksAtExit()
+
+	captureOptions := dd_example.CaptureFlags()
+	deviceIdKey := flag.String(
+		"device-id-key",
+		"",
+		"Secret key of at least 16 characters used to sign device id "+
+			"cookies. Device id cookies are disabled if empty")
+	rules := dd_example.DefaultTrustRules()
+	flag.DurationVar(
+		&rules.MaxAge,
+		"device-id-max-age",
+		rules.MaxAge,
+		"Maximum age of a device id cookie")
+	var maxDrift, maxDifference int
+	flag.IntVar(
+		&maxDrift,
+		"device-id-max-drift",
+		int(rules.MaxDrift),
+		"Maximum Drift of a detection to store its device id")
+	flag.IntVar(
+		&maxDifference,
+		"device-id-max-difference",
+		int(rules.MaxDifference),
+		"Maximum Difference of a detection to store its device id")
+	flag.Float64Var(
+		&rules.CompareRate,
+		"device-id-compare-rate",
+		0.01,
+		"Share of requests resolved from a device id cookie which are also "+
+			"detected from the User-Agent to compare the results")
+	flag.Parse()
+	rules.MaxDrift = int32(maxDrift)
+	rules.MaxDifference = int32(maxDifference)
+
 	// Initialise manager
-	manager = dd.NewResourceManager()
+	tracked := dd_example.NewTrackedManager()
+	manager = tracked.ResourceManager
 	config = dd.NewConfigHash(dd.Balanced)
+	// Record the matched substrings of the User-Agent for the matched page
+	config.SetUpdateMatchedUserAgent(true)
 	fileNames := []string{"51Degrees-LiteV4.1.hash"}
 	filePath, err := dd.GetFilePath(
 		"..",
 		fileNames)
 	if err != nil {
-		log.Fatalf("Could not find any file that matches any of \"%s\".\n",
+		dd_example.Fatalf("Could not find any file that matches any of \"%s\".\n",
 			strings.Join(fileNames, ", "))
 	}
 	// Init manager
@@ -158,14 +371,40 @@ func main() {
 		"",
 		filePath)
 	if err != nil {
-		log.Fatalln("ERROR: Failed to initialize resource manager.")
+		dd_example.Fatalln("ERROR: Failed to initialize resource manager.")
 	}
 
 	// Make sure manager object will be freed after the function execution
-	defer manager.Free()
+	defer tracked.Free()
+
+	// Start capturing evidence if enabled
+	capture, err = dd_example.NewEvidenceCapture(*captureOptions)
+	if err != nil {
+		dd_example.Fatalf("ERROR: Failed to start evidence capture. %v\n", err)
+	}
+
+	// Enable device id tokens if a key is given
+	if *deviceIdKey != "" {
+		tokens, err = dd_example.NewDeviceIdTokens([]byte(*deviceIdKey), rules)
+		if err != nil {
+			dd_example.Fatalf("ERROR: Failed to enable device id cookies. %v\n", err)
+		}
+		http.HandleFunc("/device-id/metrics", metricsHandler)
+	}
 
+	http.HandleFunc("/matched", matchedHandler)
 	http.HandleFunc("/", handler)
 	const port = 8000
 	fmt.Printf("Server listening on port: %d\n", port)
-	log.Fatal(http.ListenAndServe(fmt.Sprintf("localhost:%d", port), nil))
+	err = dd_example.ListenAndServeUntilSignal(fmt.Sprintf("localhost:%d", port), nil)
+
+	// Finish the capture file once no more requests are being handled
+	if capture != nil {
+		if err := capture.Close(); err != nil {
+			log.Printf("ERROR: Failed to finish evidence capture. %v\n", err)
+		}
+	}
+	if err != nil {
+		dd_example.Fatal(err)
+	}
 }
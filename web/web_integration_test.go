@@ -33,9 +33,13 @@ import (
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"strings"
 	"testing"
+	"time"
+
+	dd_example "github.com/51Degrees/device-detection-examples-go/v4/dd"
 
 	"github.com/51Degrees/device-detection-go/v4/dd"
 )
@@ -44,6 +48,7 @@ func TestMain(m *testing.M) {
 	// Initialise manager
 	manager = dd.NewResourceManager()
 	config = dd.NewConfigHash(dd.Balanced)
+	config.SetUpdateMatchedUserAgent(true)
 	dataFiles := []string{"51Degrees-LiteV4.1.hash"}
 	filePath, err := dd.GetFilePath("..", dataFiles)
 	if err != nil {
@@ -73,8 +78,14 @@ func TestMain(m *testing.M) {
 func TestHandler(t *testing.T) {
 	// Expected response body
 	p := &Page{
-		"Mobile Safari",
-		"640",
+		dd_example.PropertyValue{
+			Property: dd_example.PropertyBrowserName,
+			Values:   []string{"Mobile Safari"},
+		},
+		dd_example.PropertyValue{
+			Property: dd_example.PropertyScreenPixelsWidth,
+			Values:   []string{"640"},
+		},
 	}
 
 	// Construct the template
@@ -122,3 +133,70 @@ func TestHandler(t *testing.T) {
 			"\"\n", exp, act)
 	}
 }
+
+// Test that a device id cookie is issued on the first request and used
+// instead of the User-Agent on the next.
+func TestDeviceIdCookie(t *testing.T) {
+	var err error
+	tokens, err = dd_example.NewDeviceIdTokens(
+		[]byte("0123456789abcdef"),
+		dd_example.TrustRules{MaxAge: time.Hour, MaxDifference: 100, CompareRate: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { tokens = nil }()
+
+	const ua = "Mozilla/5.0 (iPhone; CPU iPhone OS 7_1 like Mac OS X) " +
+		"AppleWebKit/537.51.2 (KHTML, like Gecko) Version/7.0 Mobile/11D167 " +
+		"Safari/9537.53"
+	serve := func(cookies []*http.Cookie) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Add("User-Agent", ua)
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(handler).ServeHTTP(rr, r)
+		return rr
+	}
+
+	first := serve(nil)
+	cookies := first.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != deviceIdCookie {
+		t.Fatalf("Expected a '%s' cookie, but got %v", deviceIdCookie, cookies)
+	}
+	second := serve(cookies)
+	if second.Body.String() != first.Body.String() {
+		t.Errorf("Expected the same page from the device id cookie. Got:\n%s\n"+
+			"Expected:\n%s\n", second.Body.String(), first.Body.String())
+	}
+
+	m := tokens.Metrics()
+	if m.Issued != 1 || m.Accepted != 1 || m.DeviceIdDetections != 1 ||
+		m.Compared != 1 || m.Agreed != 1 {
+		t.Errorf("Unexpected metrics %+v", m)
+	}
+}
+
+// Test that the matched page highlights the User-Agent given in the query.
+func TestMatchedHandler(t *testing.T) {
+	const ua = "Mozilla/5.0 (Windows NT 6.3; WOW64; rv:41.0) " +
+		"Gecko/20100101 Firefox/41.0 <script>"
+	r := httptest.NewRequest("GET", "/matched?ua="+url.QueryEscape(ua), nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(matchedHandler).ServeHTTP(rr, r)
+
+	body := rr.Body.String()
+	for _, expected := range []string{
+		`<span class="matched">`,
+		`<span class="unmatched">`,
+		"&lt;script&gt;",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected the page to contain '%s':\n%s", expected, body)
+		}
+	}
+	if strings.Contains(body, "<script>") {
+		t.Errorf("Expected the User-Agent to be escaped:\n%s", body)
+	}
+}